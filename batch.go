@@ -0,0 +1,92 @@
+package pcregexp
+
+import "runtime"
+
+// batchChunk is the number of subjects a batch worker takes at a time.
+const batchChunk = 256
+
+// Batch matches a regexp against many subjects at once, spreading them across
+// a bounded pool of goroutines. Each goroutine reuses a single match data
+// block for all the subjects it handles, and results are ordered like the
+// subjects whatever the number of goroutines.
+//
+// The zero value is not usable; see [PCREgexp.Batch].
+type Batch struct {
+	re      *PCREgexp
+	workers int
+}
+
+// Batch returns a [Batch] using up to workers goroutines, or GOMAXPROCS
+// goroutines if workers <= 0. With one worker, subjects are matched on the
+// calling goroutine.
+func (re *PCREgexp) Batch(workers int) Batch {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return Batch{re: re, workers: workers}
+}
+
+// MatchStrings reports whether the regexp matches each of lines.
+func (b Batch) MatchStrings(lines []string) []bool {
+	matched := make([]bool, len(lines))
+
+	b.run(len(lines), func(m *matchData, i int) {
+		matched[i] = b.re.exec(m, stringToBytesUnsafe(lines[i]), 0, 0) >= 0
+	})
+
+	return matched
+}
+
+// FilterStrings returns the lines the regexp matches, in their original order.
+func (b Batch) FilterStrings(lines []string) []string {
+	var filtered []string
+	for i, ok := range b.MatchStrings(lines) {
+		if ok {
+			filtered = append(filtered, lines[i])
+		}
+	}
+
+	return filtered
+}
+
+// FindAllStringIndexBatch returns, for each of lines, what
+// [PCREgexp.FindAllStringIndex] would return for it.
+func (b Batch) FindAllStringIndexBatch(lines []string, n int) [][][]int {
+	results := make([][][]int, len(lines))
+
+	b.run(len(lines), func(m *matchData, i int) {
+		var offsets []int
+		b.re.scan(m, stringToBytesUnsafe(lines[i]), n, func(match []int) bool {
+			offsets = append(offsets, match[:2]...)
+			return true
+		})
+		results[i] = chunk(offsets, 2)
+	})
+
+	return results
+}
+
+// run calls fn for each index below count, spread across the workers.
+func (b Batch) run(count int, fn func(m *matchData, i int)) {
+	b.re.parallel(b.workers, count, batchChunk, fn)
+}
+
+// MatchStrings reports whether the regexp matches each of lines, reusing one
+// match data block across them. It is the same as re.Batch(1).MatchStrings.
+func (re *PCREgexp) MatchStrings(lines []string) []bool {
+	return re.Batch(1).MatchStrings(lines)
+}
+
+// FilterStrings returns the lines the regexp matches, in their original order.
+// It is the same as re.Batch(1).FilterStrings.
+func (re *PCREgexp) FilterStrings(lines []string) []string {
+	return re.Batch(1).FilterStrings(lines)
+}
+
+// FindAllStringIndexBatch returns, for each of lines, what
+// [PCREgexp.FindAllStringIndex] would return for it. It is the same as
+// re.Batch(1).FindAllStringIndexBatch.
+func (re *PCREgexp) FindAllStringIndexBatch(lines []string, n int) [][][]int {
+	return re.Batch(1).FindAllStringIndexBatch(lines, n)
+}
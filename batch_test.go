@@ -0,0 +1,93 @@
+package pcregexp_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func batchLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		if i%3 == 0 {
+			lines[i] = fmt.Sprintf("%d ERROR disk %d full, %d left", i, i%7, i%5)
+		} else {
+			lines[i] = fmt.Sprintf("%d INFO ok", i)
+		}
+	}
+
+	return lines
+}
+
+func TestBatch(t *testing.T) {
+	re := pcregexp.MustCompile(`ERROR|\d+`)
+	defer re.Close()
+
+	lines := batchLines(2000)
+
+	wantMatch := make([]bool, len(lines))
+	wantIndex := make([][][]int, len(lines))
+	var wantFilter []string
+	for i, line := range lines {
+		wantMatch[i] = re.MatchString(line)
+		wantIndex[i] = re.FindAllStringIndex(line, 2)
+		if wantMatch[i] {
+			wantFilter = append(wantFilter, line)
+		}
+	}
+
+	for _, workers := range []int{1, 3, 0} {
+		t.Run(fmt.Sprint(workers, " workers"), func(t *testing.T) {
+			b := re.Batch(workers)
+
+			if got := b.MatchStrings(lines); !reflect.DeepEqual(got, wantMatch) {
+				t.Error("MatchStrings() differs from MatchString")
+			}
+
+			if got := b.FilterStrings(lines); !reflect.DeepEqual(got, wantFilter) {
+				t.Error("FilterStrings() differs from MatchString")
+			}
+
+			if got := b.FindAllStringIndexBatch(lines, 2); !reflect.DeepEqual(got, wantIndex) {
+				t.Error("FindAllStringIndexBatch() differs from FindAllStringIndex")
+			}
+		})
+	}
+}
+
+func TestBatch_Filter(t *testing.T) {
+	re := pcregexp.MustCompile(`^ERROR`)
+	defer re.Close()
+
+	lines := []string{"ERROR a", "INFO b", "ERROR c", ""}
+
+	if got, want := re.FilterStrings(lines), []string{"ERROR a", "ERROR c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterStrings() = %q, want %q", got, want)
+	}
+
+	if got, want := re.MatchStrings(nil), []bool{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchStrings(nil) = %v, want %v", got, want)
+	}
+}
+
+func TestBatch_Panic(t *testing.T) {
+	re := pcregexp.MustCompile(`(?C1)\d`)
+	defer re.Close()
+
+	if err := re.SetCallout(func(c *pcregexp.Callout) int {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want boom", r)
+		}
+	}()
+
+	re.Batch(4).MatchStrings(batchLines(1000))
+	t.Error("MatchStrings() did not panic")
+}
@@ -0,0 +1,182 @@
+package pcregexp
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the number of patterns kept by [Cached] unless changed
+// with [SetCacheSize].
+const DefaultCacheSize = 256
+
+// CacheStats reports the activity of the pattern cache behind [Cached].
+type CacheStats struct {
+	// Hits is the number of calls served from the cache.
+	Hits uint64
+
+	// Misses is the number of calls that had to compile the pattern.
+	Misses uint64
+
+	// Evictions is the number of patterns dropped to make room for others.
+	Evictions uint64
+
+	// Entries is the number of patterns currently cached.
+	Entries int
+
+	// Size is the maximum number of patterns the cache holds.
+	Size int
+}
+
+// cacheKey identifies a compiled pattern.
+type cacheKey struct {
+	pattern string
+	opts    Option
+}
+
+// cacheEntry is compiled code shared by the handles returned by [Cached].
+type cacheEntry struct {
+	key  cacheKey
+	code uintptr
+	pre  *prefilter
+	mem  *memAccount
+	refs int // one per open handle, plus one while cached; guarded by cache.mu
+}
+
+// cacheState is the LRU cache behind [Cached].
+type cacheState struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[cacheKey]*list.Element
+	stats   CacheStats
+}
+
+var cache = cacheState{
+	size:    DefaultCacheSize,
+	lru:     list.New(),
+	entries: make(map[cacheKey]*list.Element),
+}
+
+// Cached is like [CompileWithOptions], but shares compiled code process-wide:
+// the code of the most recently used patterns is kept in a bounded LRU cache
+// keyed on pattern and options, so that packages compiling the same pattern
+// independently only compile it once.
+//
+// Every call returns a distinct handle, safe for concurrent use, that must be
+// closed once no longer needed. Closing a handle does not affect the others:
+// the compiled code is reference counted and freed once the pattern has left
+// the cache and all of its handles are closed.
+func Cached(pattern string, opts Option) (*PCREgexp, error) {
+	key := cacheKey{pattern: pattern, opts: opts}
+
+	cache.mu.Lock()
+	if re := cache.acquire(key); re != nil {
+		cache.stats.Hits++
+		cache.mu.Unlock()
+		return re, nil
+	}
+	cache.stats.Misses++
+	cache.mu.Unlock()
+
+	// Compile outside the lock; a concurrent miss on the same key may win,
+	// in which case the call still counts as the miss it was.
+	re, err := CompileWithOptions(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if shared := cache.acquire(key); shared != nil {
+		re.Close()
+		return shared, nil
+	}
+
+	if cache.size <= 0 {
+		return re, nil
+	}
+
+	e := &cacheEntry{key: key, code: re.code, pre: re.pre, mem: re.mem, refs: 2}
+	re.shared = e
+	cache.entries[key] = cache.lru.PushFront(e)
+	cache.evict()
+
+	return re, nil
+}
+
+// acquire returns a new handle to the cached code for key, or nil if it is not
+// cached. cache.mu must be held.
+func (c *cacheState) acquire(key cacheKey) *PCREgexp {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	c.lru.MoveToFront(el)
+
+	e := el.Value.(*cacheEntry)
+	e.refs++
+
+	return &PCREgexp{pattern: key.pattern, opts: key.opts, code: e.code, pre: e.pre, mem: e.mem, shared: e}
+}
+
+// evict drops the least recently used patterns beyond the cache size.
+// cache.mu must be held.
+func (c *cacheState) evict() {
+	for c.lru.Len() > c.size {
+		e := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.entries, e.key)
+		c.stats.Evictions++
+
+		e.refs--
+		if e.refs == 0 {
+			e.free()
+		}
+	}
+}
+
+// release drops the reference of a closed handle, freeing the code if it was
+// the last one.
+func (e *cacheEntry) release() {
+	cache.mu.Lock()
+	e.refs--
+	free := e.refs == 0
+	cache.mu.Unlock()
+
+	if free {
+		e.free()
+	}
+}
+
+// free frees the compiled code once no longer referenced.
+func (e *cacheEntry) free() {
+	pcre2_code_free(e.code)
+	e.mem.release()
+}
+
+// SetCacheSize sets the maximum number of patterns kept by [Cached], evicting
+// the least recently used ones if needed. A size of zero or less disables
+// caching.
+func SetCacheSize(n int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	cache.size = n
+	cache.evict()
+}
+
+// CachedStats returns the statistics of the pattern cache behind [Cached].
+func CachedStats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := cache.stats
+	stats.Entries = cache.lru.Len()
+	stats.Size = cache.size
+
+	return stats
+}
@@ -0,0 +1,107 @@
+package pcregexp_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestCached(t *testing.T) {
+	defer pcregexp.SetCacheSize(pcregexp.DefaultCacheSize)
+	pcregexp.SetCacheSize(0) // start empty
+	pcregexp.SetCacheSize(2)
+
+	before := pcregexp.CachedStats()
+
+	a, err := pcregexp.Cached(`cached-(\d+)`, 0)
+	if err != nil {
+		t.Fatalf("Cached() error = %v", err)
+	}
+
+	b := pcregexp.MustCompile(`x`) // not cached
+	defer b.Close()
+
+	c, err := pcregexp.Cached(`cached-(\d+)`, 0)
+	if err != nil {
+		t.Fatalf("Cached() error = %v", err)
+	}
+	defer c.Close()
+
+	d, err := pcregexp.Cached(`cached-(\d+)`, pcregexp.Caseless)
+	if err != nil {
+		t.Fatalf("Cached() error = %v", err)
+	}
+	defer d.Close()
+
+	stats := pcregexp.CachedStats()
+	if hits, misses := stats.Hits-before.Hits, stats.Misses-before.Misses; hits != 1 || misses != 2 {
+		t.Errorf("hits, misses = %d, %d, want 1, 2", hits, misses)
+	}
+	if stats.Entries != 2 || stats.Size != 2 {
+		t.Errorf("Entries, Size = %d, %d, want 2, 2", stats.Entries, stats.Size)
+	}
+
+	// Closing one handle must not free the code shared with the other.
+	a.Close()
+	a.Close()
+	if !c.MatchString("cached-42") {
+		t.Errorf("MatchString() on shared handle = false, want true")
+	}
+	if !d.MatchString("CACHED-42") || c.MatchString("CACHED-42") {
+		t.Errorf("options are not part of the cache key")
+	}
+
+	// Evict both entries while c and d are still open.
+	e, err := pcregexp.Cached(`other`, 0)
+	if err != nil {
+		t.Fatalf("Cached() error = %v", err)
+	}
+	defer e.Close()
+
+	pcregexp.SetCacheSize(1)
+
+	stats = pcregexp.CachedStats()
+	if evictions := stats.Evictions - before.Evictions; evictions != 2 {
+		t.Errorf("evictions = %d, want 2", evictions)
+	}
+	if !c.MatchString("cached-42") || !d.MatchString("CACHED-42") {
+		t.Errorf("MatchString() on evicted handles = false, want true")
+	}
+
+	if _, err := pcregexp.Cached(`a[`, 0); err == nil {
+		t.Errorf("Cached(%q) error = nil, want error", `a[`)
+	}
+}
+
+func TestCached_Concurrent(t *testing.T) {
+	before := pcregexp.CachedStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				re, err := pcregexp.Cached(`p([a-z]+)ch`, 0)
+				if err != nil {
+					t.Errorf("Cached() error = %v", err)
+					return
+				}
+
+				if got := re.FindStringSubmatch("peach"); len(got) != 2 || got[1] != "ea" {
+					t.Errorf("FindStringSubmatch() = %q, want [peach ea]", got)
+				}
+				re.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every call is either a hit or a miss, even when racing to compile.
+	stats := pcregexp.CachedStats()
+	if calls := (stats.Hits - before.Hits) + (stats.Misses - before.Misses); calls != 800 {
+		t.Errorf("hits + misses = %d, want 800", calls)
+	}
+}
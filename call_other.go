@@ -0,0 +1,24 @@
+//go:build !darwin && !freebsd && !(linux && (amd64 || arm64))
+// +build !darwin
+// +build !freebsd
+// +build !linux !amd64,!arm64
+
+package pcregexp
+
+import "github.com/ebitengine/purego"
+
+// nativeCall holds the arguments of a call into C. A local array would escape
+// to the heap on every call, as purego.SyscallN keeps its arguments alive;
+// the one allocation SyscallN makes for its own argument block remains.
+type nativeCall struct {
+	args [7]uintptr
+}
+
+// call7 calls the C function fn with seven integer arguments and returns its
+// result.
+func (c *nativeCall) call7(fn, a1, a2, a3, a4, a5, a6, a7 uintptr) uintptr {
+	c.args = [7]uintptr{a1, a2, a3, a4, a5, a6, a7}
+	r1, _, _ := purego.SyscallN(fn, c.args[:]...)
+
+	return r1
+}
@@ -0,0 +1,36 @@
+//go:build darwin || freebsd || (linux && amd64) || (linux && arm64)
+// +build darwin freebsd linux,amd64 linux,arm64
+
+package pcregexp
+
+import (
+	"unsafe"
+
+	_ "github.com/ebitengine/purego" // for syscall15XABI0
+)
+
+// nativeCall is the argument block of the purego trampoline calling C
+// functions of up to 15 arguments, laid out like its syscall15Args. Calling
+// through a block that lives in the match data, rather than through
+// purego.SyscallN, whose block escapes to the heap, keeps matches from
+// allocating.
+type nativeCall struct {
+	fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15 uintptr
+	f1, f2, f3, f4, f5, f6, f7, f8                                       uintptr
+	arm64R8                                                              uintptr
+}
+
+//go:linkname runtime_cgocall runtime.cgocall
+func runtime_cgocall(fn uintptr, arg unsafe.Pointer) int32
+
+//go:linkname syscall15XABI0 github.com/ebitengine/purego.syscall15XABI0
+var syscall15XABI0 uintptr
+
+// call7 calls the C function fn with seven integer arguments and returns its
+// result.
+func (c *nativeCall) call7(fn, a1, a2, a3, a4, a5, a6, a7 uintptr) uintptr {
+	*c = nativeCall{fn: fn, a1: a1, a2: a2, a3: a3, a4: a4, a5: a5, a6: a6, a7: a7}
+	runtime_cgocall(syscall15XABI0, unsafe.Pointer(c))
+
+	return c.a1
+}
@@ -0,0 +1,342 @@
+package pcregexp
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// Values a [CalloutFunc] returns to steer the match.
+const (
+	// CalloutContinue lets the match proceed normally.
+	CalloutContinue = 0
+
+	// CalloutFail fails the match at the current point, forcing PCRE2 to
+	// backtrack and try the next alternative, as if a (*FAIL) was met.
+	CalloutFail = 1
+
+	// CalloutAbort abandons the whole match attempt. Any other negative value
+	// has the same effect.
+	CalloutAbort = -37 // PCRE2_ERROR_CALLOUT
+)
+
+// CalloutFlags describes why a callout was invoked.
+type CalloutFlags uint32
+
+const (
+	// CalloutStartMatch is set for the first callout after the start of
+	// matching moved along in the subject.
+	CalloutStartMatch CalloutFlags = 1 << iota // PCRE2_CALLOUT_STARTMATCH
+
+	// CalloutBacktrack is set when there has been a backtrack since the
+	// previous callout, or since the start of matching.
+	CalloutBacktrack // PCRE2_CALLOUT_BACKTRACK
+)
+
+// Callout describes the state of a match when a callout point such as (?C1)
+// or (?C'name') is reached.
+//
+// A Callout is only valid for the duration of the [CalloutFunc] call; Subject
+// aliases the subject being matched and must not be modified or retained.
+type Callout struct {
+	// Number is the callout number, 0 to 255. String callouts and automatic
+	// callouts have numbers 0 and 255 respectively.
+	Number int
+
+	// String is the text of a string callout such as (?C'name'), without the
+	// delimiters, or "" for numbered callouts.
+	String string
+
+	// StringOffset is the offset in the pattern of String.
+	StringOffset int
+
+	// Subject is the subject being matched.
+	Subject []byte
+
+	// Start is the offset in Subject where the current match attempt began.
+	Start int
+
+	// Position is the current offset in Subject.
+	Position int
+
+	// PatternPosition is the offset in the pattern of the next item to be
+	// matched.
+	PatternPosition int
+
+	// NextItemLength is the length of the next item to be matched in the
+	// pattern.
+	NextItemLength int
+
+	// CaptureTop is one more than the number of the highest numbered group
+	// captured so far.
+	CaptureTop int
+
+	// CaptureLast is the number of the most recently closed group, or 0 if
+	// none has been closed yet.
+	CaptureLast int
+
+	// Offsets holds the index pairs of the groups below CaptureTop, laid out
+	// like the result of [PCREgexp.FindSubmatchIndex]. The first pair is
+	// Start and Position; unset groups are reported as -1.
+	Offsets []int
+
+	// Mark is the most recently passed (*MARK) name, or "".
+	Mark string
+
+	// Flags tells why the callout was invoked.
+	Flags CalloutFlags
+}
+
+// CalloutFunc is called at every callout point of a pattern while matching.
+// It returns [CalloutContinue], [CalloutFail] or [CalloutAbort].
+type CalloutFunc func(c *Callout) int
+
+// calloutBlock mirrors pcre2_callout_block.
+type calloutBlock struct {
+	version             uint32
+	calloutNumber       uint32
+	captureTop          uint32
+	captureLast         uint32
+	offsetVector        *uint64
+	mark                *uint8
+	subject             *uint8
+	subjectLength       uint64
+	startMatch          uint64
+	currentPosition     uint64
+	patternPosition     uint64
+	nextItemLength      uint64
+	calloutStringOffset uint64
+	calloutStringLength uint64
+	calloutString       *uint8
+	calloutFlags        uint32
+}
+
+// calloutHandler is the Go side of a match context's callout_data. Each match
+// context has its own, so that a panic is re-raised by the match it aborted.
+type calloutHandler struct {
+	fn       CalloutFunc
+	handle   uintptr
+	panicked any // recovered from fn, re-raised once pcre2_match returns
+}
+
+// calloutEnumerateBlock mirrors pcre2_callout_enumerate_block.
+type calloutEnumerateBlock struct {
+	version             uint32
+	patternPosition     uint64
+	nextItemLength      uint64
+	calloutNumber       uint32
+	calloutStringOffset uint64
+	calloutStringLength uint64
+	calloutString       *uint8
+}
+
+// CalloutInfo describes a callout point of a compiled pattern.
+type CalloutInfo struct {
+	// Number is the callout number, 0 for string callouts and 255 for
+	// automatic callouts.
+	Number int
+
+	// String is the text of a string callout, without the delimiters, or ""
+	// for numbered callouts.
+	String string
+
+	// StringOffset is the offset in the pattern of String.
+	StringOffset int
+
+	// PatternOffset is the offset in the pattern of the item that follows
+	// the callout.
+	PatternOffset int
+
+	// NextItemLength is the length of the item that follows the callout.
+	NextItemLength int
+}
+
+var (
+	calloutOnce     sync.Once
+	calloutCallback uintptr
+
+	enumerateOnce     sync.Once
+	enumerateCallback uintptr
+)
+
+// calloutFunction returns the C function pointer given to pcre2_set_callout.
+//
+// purego callbacks are a scarce, never released resource, so a single
+// trampoline is shared by every regexp and dispatches on callout_data.
+func calloutFunction() uintptr {
+	calloutOnce.Do(func() {
+		calloutCallback = purego.NewCallback(callout)
+	})
+
+	return calloutCallback
+}
+
+// enumerateFunction returns the C function pointer given to
+// pcre2_callout_enumerate.
+func enumerateFunction() uintptr {
+	enumerateOnce.Do(func() {
+		enumerateCallback = purego.NewCallback(enumerate)
+	})
+
+	return enumerateCallback
+}
+
+// enumerate is invoked by pcre2_callout_enumerate for every callout point; data
+// is the handle of the *[]CalloutInfo being filled.
+func enumerate(block *calloutEnumerateBlock, data uintptr) uintptr {
+	infos, _ := loadHandle(data).(*[]CalloutInfo)
+	if infos == nil {
+		return 0
+	}
+
+	info := CalloutInfo{
+		Number:         int(block.calloutNumber),
+		PatternOffset:  int(block.patternPosition),
+		NextItemLength: int(block.nextItemLength),
+	}
+
+	if block.calloutString != nil {
+		info.String = string(unsafe.Slice(block.calloutString, block.calloutStringLength))
+		info.StringOffset = int(block.calloutStringOffset)
+	}
+
+	*infos = append(*infos, info)
+
+	return 0
+}
+
+// callout is invoked by PCRE2 for every callout point.
+func callout(block *calloutBlock, data uintptr) (ret uintptr) {
+	h, _ := loadHandle(data).(*calloutHandler)
+	if h == nil || h.fn == nil {
+		return CalloutContinue
+	}
+
+	// A panic must not unwind through the C frames of pcre2_match.
+	defer func() {
+		if r := recover(); r != nil {
+			h.panicked = r
+			ret = calloutReturn(CalloutAbort)
+		}
+	}()
+
+	return calloutReturn(h.fn(newCallout(block)))
+}
+
+// calloutReturn converts a callout result to the C int PCRE2 expects.
+func calloutReturn(v int) uintptr {
+	return uintptr(v)
+}
+
+// newCallout copies the state described by block into a [Callout].
+func newCallout(block *calloutBlock) *Callout {
+	c := &Callout{
+		Number:          int(block.calloutNumber),
+		Start:           int(block.startMatch),
+		Position:        int(block.currentPosition),
+		PatternPosition: int(block.patternPosition),
+		NextItemLength:  int(block.nextItemLength),
+		CaptureTop:      int(block.captureTop),
+		CaptureLast:     int(block.captureLast),
+	}
+
+	if block.subject != nil && block.subjectLength > 0 {
+		c.Subject = unsafe.Slice(block.subject, block.subjectLength)
+	}
+
+	if block.offsetVector != nil && block.captureTop > 0 {
+		ovector := unsafe.Slice(block.offsetVector, 2*block.captureTop)
+		c.Offsets = make([]int, len(ovector))
+		for i, v := range ovector {
+			c.Offsets[i] = int(v)
+		}
+		c.Offsets[0], c.Offsets[1] = c.Start, c.Position
+	}
+
+	if block.mark != nil {
+		// The name is NUL-terminated.
+		n := 0
+		for p := unsafe.Pointer(block.mark); *(*byte)(p) != 0; p = unsafe.Add(p, 1) {
+			n++
+		}
+		c.Mark = string(unsafe.Slice(block.mark, n))
+	}
+
+	if block.version >= 1 && block.calloutString != nil {
+		c.String = string(unsafe.Slice(block.calloutString, block.calloutStringLength))
+		c.StringOffset = int(block.calloutStringOffset)
+	}
+
+	if block.version >= 2 {
+		c.Flags = CalloutFlags(block.calloutFlags)
+	}
+
+	return c
+}
+
+// SetCallout registers fn to be called at every callout point of the pattern,
+// such as (?C1) or (?C'name'). It can be used to implement custom predicates,
+// e.g. `(\d+\.\d+\.\d+\.\d+)(?C'isValidIP')`, or to instrument matching.
+//
+// Passing nil removes the callout function, after which callout points in the
+// pattern are ignored. A panic raised by fn aborts the match and is re-raised
+// by the matching method that ran into it once PCRE2 has returned.
+//
+// SetCallout may be called while the regexp is in use: matches already
+// running keep calling the function they started with.
+func (re *PCREgexp) SetCallout(fn CalloutFunc) error {
+	if re.code == 0 {
+		return fmt.Errorf("SetCallout called on a closed regexp")
+	}
+
+	re.mu.Lock()
+	re.calloutFn = fn
+	re.mu.Unlock()
+
+	return nil
+}
+
+// calloutFunc returns the callout function of the regexp, or nil.
+func (re *PCREgexp) calloutFunc() CalloutFunc {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+
+	return re.calloutFn
+}
+
+// repanic re-raises a panic recovered from the callout function during the
+// last match run with h.
+func (h *calloutHandler) repanic() {
+	if h == nil || h.panicked == nil {
+		return
+	}
+
+	p := h.panicked
+	h.panicked = nil
+
+	panic(p)
+}
+
+// Callouts returns the callout points of the compiled pattern in the order
+// they appear, without matching anything. It lets callers audit patterns, for
+// instance to reject the ones referencing callouts they do not provide.
+//
+// It returns nil if the pattern has no callouts or the regexp is closed.
+func (re *PCREgexp) Callouts() []CalloutInfo {
+	if re.code == 0 {
+		return nil
+	}
+
+	var infos []CalloutInfo
+
+	h := newHandle(&infos)
+	defer deleteHandle(h)
+
+	if pcre2_callout_enumerate(re.code, enumerateFunction(), h) != 0 {
+		return nil
+	}
+
+	return infos
+}
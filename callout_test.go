@@ -0,0 +1,228 @@
+package pcregexp_test
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestRegexp_SetCallout(t *testing.T) {
+	t.Run("Predicate", func(t *testing.T) {
+		re := pcregexp.MustCompile(`\b(\d{1,3}(?:\.\d{1,3}){3})(?C'isValidIP')\b`)
+		defer re.Close()
+
+		err := re.SetCallout(func(c *pcregexp.Callout) int {
+			if c.String != "isValidIP" {
+				return pcregexp.CalloutContinue
+			}
+
+			ip := c.Subject[c.Offsets[2]:c.Offsets[3]]
+			if net.ParseIP(string(ip)) == nil {
+				return pcregexp.CalloutFail
+			}
+
+			return pcregexp.CalloutContinue
+		})
+		if err != nil {
+			t.Fatalf("SetCallout() error = %v", err)
+		}
+
+		input := "999.1.1.1 10.0.0.1"
+		if got, want := re.FindString(input), "10.0.0.1"; got != want {
+			t.Errorf("FindString(%q) = %q, want %q", input, got, want)
+		}
+	})
+
+	t.Run("Numbers", func(t *testing.T) {
+		re := pcregexp.MustCompile(`a(?C1)b(?C2)`)
+		defer re.Close()
+
+		var got []int
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			got = append(got, c.Number, c.Position)
+			return pcregexp.CalloutContinue
+		})
+
+		if !re.MatchString("xab") {
+			t.Fatalf("MatchString(%q) = false, want true", "xab")
+		}
+
+		if want := []int{1, 2, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("callouts = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Mark", func(t *testing.T) {
+		re := pcregexp.MustCompile(`(?:(*MARK:first)a|(*MARK:second)b)(?C1)`)
+		defer re.Close()
+
+		var got []string
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			got = append(got, c.Mark)
+			return pcregexp.CalloutContinue
+		})
+
+		if re.FindAllString("a b", -1) == nil {
+			t.Fatalf("FindAllString(%q) = nil, want matches", "a b")
+		}
+
+		if want := []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("marks = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		re := pcregexp.MustCompile(`a(?C1)b`)
+		defer re.Close()
+
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			return pcregexp.CalloutAbort
+		})
+
+		if re.MatchString("ab") {
+			t.Errorf("MatchString(%q) = true, want false", "ab")
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		re := pcregexp.MustCompile(`a(?C1)b`)
+		defer re.Close()
+
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			return pcregexp.CalloutFail
+		})
+		re.SetCallout(nil)
+
+		if !re.MatchString("ab") {
+			t.Errorf("MatchString(%q) = false, want true", "ab")
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		re := pcregexp.MustCompile(`a(?C1)b`)
+		defer re.Close()
+
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			panic("boom")
+		})
+
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want %q", r, "boom")
+			}
+		}()
+
+		re.MatchString("ab")
+	})
+
+	t.Run("ConcurrentPanic", func(t *testing.T) {
+		re := pcregexp.MustCompile(`\w+(?C1)`)
+		defer re.Close()
+
+		re.SetCallout(func(c *pcregexp.Callout) int {
+			if c.Subject[0] == 'p' {
+				panic("boom")
+			}
+
+			return pcregexp.CalloutContinue
+		})
+
+		// Each match must see its own panic, and only its own.
+		var wg sync.WaitGroup
+		errs := make(chan string, 8)
+
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+
+				for i := 0; i < 2000; i++ {
+					subject := "ok"
+					if (g+i)%2 == 0 {
+						subject = "panic"
+					}
+
+					panicked, matched := func() (panicked any, matched bool) {
+						defer func() { panicked = recover() }()
+						return nil, re.MatchString(subject)
+					}()
+
+					if wantPanic := subject == "panic"; (panicked != nil) != wantPanic || (!wantPanic && !matched) {
+						errs <- fmt.Sprintf("MatchString(%q) = %v, panic %v", subject, matched, panicked)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+
+	t.Run("ConcurrentSet", func(t *testing.T) {
+		re := pcregexp.MustCompile(`a(?C1)b`)
+		defer re.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			for i := 0; i < 1000; i++ {
+				re.SetCallout(func(c *pcregexp.Callout) int {
+					return pcregexp.CalloutContinue
+				})
+				re.SetCallout(nil)
+			}
+		}()
+
+		for i := 0; i < 1000; i++ {
+			if !re.MatchString("ab") {
+				t.Fatalf("MatchString(%q) = false, want true", "ab")
+			}
+		}
+		<-done
+	})
+}
+
+func TestRegexp_Callouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []pcregexp.CalloutInfo
+	}{
+		{"none", `p([a-z]+)ch`, nil},
+		{
+			"numbered",
+			`a(?C1)b(?C2)`,
+			[]pcregexp.CalloutInfo{
+				{Number: 1, PatternOffset: 6, NextItemLength: 1},
+				{Number: 2, PatternOffset: 12, NextItemLength: 0},
+			},
+		},
+		{
+			"string",
+			`(\d+)(?C'isValidIP')x`,
+			[]pcregexp.CalloutInfo{
+				{String: "isValidIP", StringOffset: 9, PatternOffset: 20, NextItemLength: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := pcregexp.MustCompile(tt.pattern)
+			defer re.Close()
+
+			if got := re.Callouts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Callouts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package pcregexp
+
+// captureHistory records every span captured by each group during a match.
+type captureHistory struct {
+	groups [][][]int // per group, the spans captured on the current path
+	last   []int     // capture offsets seen at the previous callout
+	start  int       // start of the current match attempt
+	fn     CalloutFunc
+	auto   bool // whether fn asked for automatic callouts itself
+}
+
+// callout observes the capture state at every automatic callout, then hands
+// over to the regexp's own callout function, if any.
+func (h *captureHistory) callout(c *Callout) int {
+	if c.Flags&CalloutStartMatch != 0 || c.Start != h.start {
+		for g := range h.groups {
+			h.groups[g] = h.groups[g][:0]
+		}
+		h.last = nil
+		h.start = c.Start
+	}
+
+	for g := 1; g < c.CaptureTop && g < len(h.groups); g++ {
+		start, end := c.Offsets[2*g], c.Offsets[2*g+1]
+		if start < 0 {
+			continue
+		}
+
+		if 2*g+1 < len(h.last) && h.last[2*g] == start && h.last[2*g+1] == end {
+			continue
+		}
+
+		h.record(g, start, end)
+	}
+	h.last = c.Offsets
+
+	if h.fn == nil || (c.Number == 255 && !h.auto) {
+		return CalloutContinue
+	}
+
+	return h.fn(c)
+}
+
+// record adds the span start:end captured by group g.
+//
+// Iterations of a group close at increasing offsets, so spans ending after
+// start were undone by backtracking and are discarded. A span equal to the
+// remaining last one is the capture PCRE2 restored while backtracking.
+func (h *captureHistory) record(g, start, end int) {
+	spans := h.groups[g]
+	for len(spans) > 0 && spans[len(spans)-1][1] > start {
+		spans = spans[:len(spans)-1]
+	}
+
+	if n := len(spans); n > 0 && spans[n-1][0] == start && spans[n-1][1] == end {
+		h.groups[g] = spans
+		return
+	}
+
+	h.groups[g] = append(spans, []int{start, end})
+}
+
+// finish trims the history so that it agrees with the final match: the last
+// span of each group is the one reported by PCRE2, and groups unset by the
+// match have no spans.
+func (h *captureHistory) finish(match []int) [][][]int {
+	for g := range h.groups {
+		if 2*g+1 >= len(match) || match[2*g] < 0 {
+			h.groups[g] = nil
+			continue
+		}
+
+		start, end := match[2*g], match[2*g+1]
+		spans := h.groups[g]
+
+		i := len(spans) - 1
+		for i >= 0 && (spans[i][0] != start || spans[i][1] != end) {
+			i--
+		}
+
+		if i < 0 {
+			h.groups[g] = [][]int{{start, end}}
+		} else {
+			h.groups[g] = spans[:i+1]
+		}
+	}
+
+	return h.groups
+}
+
+// historyRegexp returns a copy of the regexp compiled with automatic callouts,
+// used to observe captures as they happen.
+func (re *PCREgexp) historyRegexp() (*PCREgexp, error) {
+	if re.history == nil {
+		history, err := CompileWithOptions(re.pattern, re.opts|AutoCallout)
+		if err != nil {
+			return nil, err
+		}
+		re.history = history
+	}
+
+	return re.history, nil
+}
+
+// FindAllCaptures returns every span captured by each group during the
+// leftmost match of the regexp in s, not just the last one, like the Captures
+// of a .NET Group. For instance, `(?:(\d+),?)+` matched against "1,22,333"
+// yields the spans of "1", "22" and "333" for group 1.
+//
+// The result is indexed by group number; element 0 holds the span of the
+// whole match. Each span is a two-element slice of start and end offsets.
+// Iterations undone by backtracking are not reported, and a group that did not
+// participate in the match has no spans. A return value of nil indicates no
+// match.
+//
+// Captures are observed through automatic callouts on a separately compiled
+// copy of the pattern, so this is much slower than [PCREgexp.FindStringSubmatchIndex].
+// Callouts registered with [PCREgexp.SetCallout] are still invoked, and
+// concurrent calls are serialized.
+func (re *PCREgexp) FindAllCaptures(s string) [][][]int {
+	re.historyMu.Lock()
+	defer re.historyMu.Unlock()
+
+	if re.code == 0 {
+		return nil
+	}
+
+	history, err := re.historyRegexp()
+	if err != nil {
+		return nil
+	}
+
+	h := &captureHistory{
+		groups: make([][][]int, re.NumSubexp()+1),
+		start:  -1,
+		auto:   re.opts&AutoCallout != 0,
+	}
+	h.fn = re.calloutFunc()
+
+	if err := history.SetCallout(h.callout); err != nil {
+		return nil
+	}
+	defer history.SetCallout(nil)
+
+	match := history.FindStringSubmatchIndex(s)
+	if match == nil {
+		return nil
+	}
+
+	return h.finish(match)
+}
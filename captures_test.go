@@ -0,0 +1,101 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestRegexp_FindAllCaptures(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		input   string
+		want    [][][]int
+	}{
+		{
+			"repeated group",
+			`(?:(\d+),?)+`,
+			"1,22,333",
+			[][][]int{{{0, 8}}, {{0, 1}, {2, 4}, {5, 8}}},
+		},
+		{
+			"backtracked iteration",
+			`(?:(\d+),)+(\d+)$`,
+			"1,22,333",
+			[][][]int{{{0, 8}}, {{0, 1}, {2, 4}}, {{5, 8}}},
+		},
+		{
+			"shortened iteration",
+			`(?:(a+)b?)+ab`,
+			"aabaab",
+			[][][]int{{{0, 6}}, {{0, 2}, {3, 4}}},
+		},
+		{
+			"unset group",
+			`(?:(a)|b)+(c)?`,
+			"aba",
+			[][][]int{{{0, 3}}, {{0, 1}, {2, 3}}, nil},
+		},
+		{"no match", `(\d)+`, "abc", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := pcregexp.MustCompile(tt.pattern)
+			defer re.Close()
+
+			if got := re.FindAllCaptures(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAllCaptures(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegexp_FindAllCaptures_Callout(t *testing.T) {
+	re := pcregexp.MustCompile(`(?:(\d)(?C1))+`)
+	defer re.Close()
+
+	calls := 0
+	re.SetCallout(func(c *pcregexp.Callout) int {
+		calls++
+		return pcregexp.CalloutContinue
+	})
+
+	want := [][][]int{{{0, 3}}, {{0, 1}, {1, 2}, {2, 3}}}
+	if got := re.FindAllCaptures("123"); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllCaptures(%q) = %v, want %v", "123", got, want)
+	}
+
+	if calls != 3 {
+		t.Errorf("callout called %d times, want 3", calls)
+	}
+}
+
+func TestRegexp_FindAllCaptures_Close(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		re := pcregexp.MustCompile(`(?:(\d+),?)+`)
+
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				// Either the captures, or nil once the regexp is closed.
+				if got := re.FindAllCaptures("1,22,333"); got != nil && len(got[1]) != 3 {
+					t.Errorf("FindAllCaptures() = %v, want 3 captures of group 1", got)
+				}
+			}()
+		}
+
+		re.Close()
+		wg.Wait()
+
+		if got := re.FindAllCaptures("1,22,333"); got != nil {
+			t.Errorf("FindAllCaptures() after Close = %v, want nil", got)
+		}
+	}
+}
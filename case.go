@@ -0,0 +1,128 @@
+package pcregexp
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// caseShape is the letter case pattern of a text.
+type caseShape int
+
+const (
+	caseNone  caseShape = iota // no cased letters
+	caseLower                  // all lower case
+	caseUpper                  // all upper case
+	caseTitle                  // upper case first letter, then lower case
+	caseMixed                  // anything else
+)
+
+// shapeOf returns the case shape of b.
+func shapeOf(b []byte) caseShape {
+	var letters, upper int
+	firstUpper := false
+
+	for len(b) > 0 {
+		r, width := utf8.DecodeRune(b)
+		b = b[width:]
+
+		switch {
+		case unicode.IsUpper(r) || unicode.IsTitle(r):
+			firstUpper = firstUpper || letters == 0
+			upper++
+		case unicode.IsLower(r):
+		default:
+			continue
+		}
+		letters++
+	}
+
+	switch {
+	case letters == 0:
+		return caseNone
+	case upper == 0:
+		return caseLower
+	case upper == 1 && firstUpper:
+		// A single upper case letter, as in "I", is taken for a title.
+		return caseTitle
+	case upper == letters:
+		return caseUpper
+	default:
+		return caseMixed
+	}
+}
+
+// appendCase appends repl to dst, in the case shape of match. With a mixed
+// shape, each rune of repl takes the case of the rune at the same position
+// in match, or of the last letter of match past its end.
+func appendCase(dst, repl, match []byte) []byte {
+	shape := shapeOf(match)
+	first := true
+
+	var last rune // last letter of match, for runes of repl past its end
+
+	for len(repl) > 0 {
+		r, width := utf8.DecodeRune(repl)
+		orig := repl[:width]
+		repl = repl[width:]
+
+		m := last
+		if shape == caseMixed && len(match) > 0 {
+			var n int
+			m, n = utf8.DecodeRune(match)
+			match = match[n:]
+
+			if unicode.IsLetter(m) {
+				last = m
+			}
+		}
+
+		if !unicode.IsLetter(r) {
+			dst = append(dst, orig...)
+			continue
+		}
+
+		switch shape {
+		case caseLower:
+			r = unicode.ToLower(r)
+		case caseUpper:
+			r = unicode.ToUpper(r)
+		case caseTitle:
+			if first {
+				r = unicode.ToTitle(r)
+			} else {
+				r = unicode.ToLower(r)
+			}
+		case caseMixed:
+			switch {
+			case unicode.IsUpper(m) || unicode.IsTitle(m):
+				r = unicode.ToUpper(r)
+			case unicode.IsLower(m):
+				r = unicode.ToLower(r)
+			}
+		}
+		first = false
+
+		dst = utf8.AppendRune(dst, r)
+	}
+
+	return dst
+}
+
+// ReplaceAllPreserveCase returns a copy of src in which all matches of the
+// regexp have been replaced by repl, in the letter case of each match: all
+// lower case, all upper case, or title case as in "Colour". For matches mixing
+// cases otherwise, each letter of repl takes the case of the letter at the
+// same position in the match, and past the end of the match that of its last
+// letter. repl is used as is for matches without letters.
+func (re *PCREgexp) ReplaceAllPreserveCase(src, repl []byte) []byte {
+	return re.replaceAll(make([]byte, 0, len(src)), src, func(dst []byte, match []int) []byte {
+		return appendCase(dst, repl, src[match[0]:match[1]])
+	})
+}
+
+// ReplaceAllStringPreserveCase is like [PCREgexp.ReplaceAllPreserveCase] but
+// works on strings.
+func (re *PCREgexp) ReplaceAllStringPreserveCase(src, repl string) string {
+	b := re.ReplaceAllPreserveCase(stringToBytesUnsafe(src), stringToBytesUnsafe(repl))
+	return bytesToStringUnsafe(b)
+}
@@ -0,0 +1,56 @@
+package pcregexp_test
+
+import (
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestReplaceAllPreserveCase(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`colou?r`, pcregexp.Caseless)
+	defer re.Close()
+
+	tests := []struct {
+		src, repl, want string
+	}{
+		{"color", "colour", "colour"},
+		{"Color", "colour", "Colour"},
+		{"COLOR", "colour", "COLOUR"},
+		{"CoLoR", "colour", "CoLoUR"},
+		{"cOLOR and color", "Colour", "cOLOUR and colour"},
+		{"no match", "colour", "no match"},
+	}
+
+	for _, tt := range tests {
+		if got := re.ReplaceAllStringPreserveCase(tt.src, tt.repl); got != tt.want {
+			t.Errorf("ReplaceAllStringPreserveCase(%q, %q) = %q, want %q", tt.src, tt.repl, got, tt.want)
+		}
+	}
+
+	digits := pcregexp.MustCompile(`\d+`)
+	defer digits.Close()
+
+	if got, want := string(digits.ReplaceAllPreserveCase([]byte("a 12 b"), []byte("N-n"))), "a N-n b"; got != want {
+		t.Errorf("ReplaceAllPreserveCase() = %q, want %q", got, want)
+	}
+}
+
+func TestTemplate_PreserveCase(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`(\w+)ize\b`, pcregexp.Caseless)
+	defer re.Close()
+
+	tmpl, err := re.CompileTemplate(`${1}ise`)
+	if err != nil {
+		t.Fatalf("CompileTemplate() error = %v", err)
+	}
+
+	got := re.ReplaceAllTemplateString("Organize, REALIZE, optimize", tmpl.PreserveCase())
+	if want := "Organise, REALISE, optimise"; got != want {
+		t.Errorf("ReplaceAllTemplateString() = %q, want %q", got, want)
+	}
+
+	// The original template is left as is.
+	if got, want := re.ReplaceAllTemplateString("REALIZE", tmpl), "REALise"; got != want {
+		t.Errorf("ReplaceAllTemplateString() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+// Command pcregexp-precompile compiles a list of patterns and writes them in
+// the format of [pcregexp.SerializePatterns], to be embedded in a program and
+// restored with [pcregexp.DeserializePatterns] instead of compiling every
+// pattern at startup.
+//
+// Usage:
+//
+//	pcregexp-precompile [-flags imsxu] [-o output] [input]
+//
+// The input, standard input by default, holds one pattern per line; empty
+// lines are skipped. The flags i, m, s, x and u compile every pattern with
+// [pcregexp.Caseless], [pcregexp.Multiline], [pcregexp.DotAll],
+// [pcregexp.Extended] and [pcregexp.UTF] respectively.
+//
+// It is meant to be run from a go:generate directive:
+//
+//	//go:generate go run github.com/dwisiswant0/pcregexp/cmd/pcregexp-precompile -o rules.bin rules.txt
+//
+//	//go:embed rules.bin
+//	var rules []byte
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func main() {
+	output := flag.String("o", "", "write the serialized patterns to `file` instead of standard output")
+	flags := flag.String("flags", "", "compile options as a set of `letters` among i, m, s, x and u")
+	flag.Parse()
+
+	if err := run(*output, *flags, flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, "pcregexp-precompile:", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, flags, input string) error {
+	opts, err := parseFlags(flags)
+	if err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var res []*pcregexp.PCREgexp
+	defer func() {
+		for _, re := range res {
+			re.Close()
+		}
+	}()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		pattern := strings.TrimSuffix(scanner.Text(), "\r")
+		if pattern == "" {
+			continue
+		}
+
+		re, err := pcregexp.CompileWithOptions(pattern, opts)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		res = append(res, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	data, err := pcregexp.SerializePatterns(res)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	return os.WriteFile(output, data, 0o644)
+}
+
+func parseFlags(flags string) (pcregexp.Option, error) {
+	var opts pcregexp.Option
+
+	for _, f := range flags {
+		switch f {
+		case 'i':
+			opts |= pcregexp.Caseless
+		case 'm':
+			opts |= pcregexp.Multiline
+		case 's':
+			opts |= pcregexp.DotAll
+		case 'x':
+			opts |= pcregexp.Extended
+		case 'u':
+			opts |= pcregexp.UTF
+		default:
+			return 0, fmt.Errorf("unknown flag %q", f)
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,35 @@
+package pcregexp
+
+// What to ask pcre2_pattern_info for.
+const (
+	pcre2InfoCaptureCount  uint32 = 4  // PCRE2_INFO_CAPTURECOUNT
+	pcre2InfoFirstCodeUnit uint32 = 5  // PCRE2_INFO_FIRSTCODEUNIT
+	pcre2InfoFirstCodeType uint32 = 6  // PCRE2_INFO_FIRSTCODETYPE
+	pcre2InfoLastCodeUnit  uint32 = 11 // PCRE2_INFO_LASTCODEUNIT
+	pcre2InfoLastCodeType  uint32 = 12 // PCRE2_INFO_LASTCODETYPE
+	pcre2InfoMinLength     uint32 = 16 // PCRE2_INFO_MINLENGTH
+	pcre2InfoNameCount     uint32 = 17 // PCRE2_INFO_NAMECOUNT
+	pcre2InfoNameEntrySize uint32 = 18 // PCRE2_INFO_NAMEENTRYSIZE
+	pcre2InfoNameTable     uint32 = 19 // PCRE2_INFO_NAMETABLE
+)
+
+// What to ask pcre2_config for.
+const (
+	pcre2ConfigVersion uint32 = 11 // PCRE2_CONFIG_VERSION
+)
+
+// Match options.
+const (
+	pcre2NoUTFCheck uint32 = 0x40000000 // PCRE2_NO_UTF_CHECK
+	pcre2Anchored   uint32 = 0x80000000 // PCRE2_ANCHORED
+)
+
+// Error codes.
+const (
+	pcre2ErrorNoMatch    int32 = -1  // PCRE2_ERROR_NOMATCH
+	pcre2ErrorDFAWSSize  int32 = -43 // PCRE2_ERROR_DFA_WSSIZE
+	pcre2ErrorNoMemory   int32 = -48 // PCRE2_ERROR_NOMEMORY
+	pcre2ErrorUTF8Err21  int32 = -23 // PCRE2_ERROR_UTF8_ERR21
+	pcre2ErrorUTF8Err1   int32 = -3  // PCRE2_ERROR_UTF8_ERR1
+	pcre2ErrorHeapFailed int32 = 121 // PCRE2_ERROR_HEAP_FAILED
+)
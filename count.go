@@ -0,0 +1,51 @@
+package pcregexp
+
+// Count returns the number of successive matches of the regexp in b, as
+// len(re.FindAllIndex(b, -1)) would, but without building the matches.
+//
+// Count does not allocate on the Go side, apart from the calls into PCRE2 on
+// the platforms where they allocate, as for [PCREgexp.Match].
+func (re *PCREgexp) Count(b []byte) int {
+	return re.CountUpTo(b, -1)
+}
+
+// CountString is like [PCREgexp.Count] but counts the matches in s.
+func (re *PCREgexp) CountString(s string) int {
+	return re.CountUpTo(stringToBytesUnsafe(s), -1)
+}
+
+// CountUpTo is like [PCREgexp.Count] but stops searching once max matches are
+// found, if max >= 0. It is meant for thresholds, such as whether a line
+// holds at least three matches.
+func (re *PCREgexp) CountUpTo(b []byte, max int) int {
+	if re.code == 0 || max == 0 {
+		return 0
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return 0
+	}
+	defer re.putMatchData(m)
+
+	// This is scan without a deliver callback, which would escape and
+	// allocate along with the count it updates.
+	count := 0
+	pos, prevEnd := 0, -1
+	options := uint32(0)
+
+	for (max < 0 || count < max) && pos <= len(b) {
+		if re.exec(m, b, pos, options) < 0 {
+			break
+		}
+		options = re.utfChecked()
+
+		match := m.ovector
+		if accepts(pos, prevEnd, match) {
+			count++
+		}
+		pos, prevEnd = re.nextPos(b, pos, match), match[1]
+	}
+
+	return count
+}
@@ -0,0 +1,43 @@
+package pcregexp
+
+import "os"
+
+// SearchFile calls fn for each successive match of re in the file at path, as
+// [PCREgexp.FindAllIndex] would find them, with the absolute offsets of the
+// match in the file and its text. The search stops early once fn returns
+// false.
+//
+// On Linux the file is memory-mapped and matched in place, so that large files
+// are searched without being read into the heap; elsewhere, and for files
+// that report no size such as those of /proc, it is read in full. Either way,
+// the text passed to fn is only valid until fn returns, and the file must not
+// be truncated while it is searched.
+func SearchFile(path string, re *PCREgexp, fn func(start, end int64, match []byte) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, release, err := mapFile(f)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if re.code == 0 {
+		return nil
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return memoryError("pcre2_match_data_create_from_pattern")
+	}
+	defer re.putMatchData(m)
+
+	re.scan(m, data, -1, func(match []int) bool {
+		return fn(int64(match[0]), int64(match[1]), data[match[0]:match[1]:match[1]])
+	})
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package pcregexp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// mapFile maps the content of f into memory, and returns it along with a
+// function unmapping it. Files that report no size, such as those of /proc,
+// FIFOs and devices, cannot be mapped and are read in full instead.
+func mapFile(f *os.File) ([]byte, func(), error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := fi.Size()
+	if size == 0 || !fi.Mode().IsRegular() {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return data, func() {}, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("%s: file too large to map", f.Name())
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: f.Name(), Err: err}
+	}
+
+	// The file is searched from start to end.
+	_ = syscall.Madvise(data, syscall.MADV_SEQUENTIAL)
+
+	return data, func() { syscall.Munmap(data) }, nil
+}
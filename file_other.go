@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package pcregexp
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the content of f, as files are only memory-mapped on Linux.
+func mapFile(f *os.File) ([]byte, func(), error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() {}, nil
+}
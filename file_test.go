@@ -0,0 +1,100 @@
+package pcregexp_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestSearchFile(t *testing.T) {
+	re := pcregexp.MustCompile(`p([a-z]+)ch`)
+	defer re.Close()
+
+	data := []byte("peach punch\npinch")
+	path := filepath.Join(t.TempDir(), "subject.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]int
+	var texts []string
+	err := pcregexp.SearchFile(path, re, func(start, end int64, match []byte) bool {
+		got = append(got, []int{int(start), int(end)})
+		texts = append(texts, string(match))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("SearchFile() error = %v", err)
+	}
+
+	if want := re.FindAllIndex(data, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchFile() found %v, want %v", got, want)
+	}
+
+	if want := []string{"peach", "punch", "pinch"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("SearchFile() texts = %q, want %q", texts, want)
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		calls := 0
+		pcregexp.SearchFile(path, re, func(start, end int64, match []byte) bool {
+			calls++
+			return false
+		})
+
+		if calls != 1 {
+			t.Errorf("fn called %d times after returning false, want 1", calls)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.txt")
+		if err := os.WriteFile(empty, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := pcregexp.SearchFile(empty, re, func(start, end int64, match []byte) bool {
+			t.Errorf("unexpected match at %d", start)
+			return true
+		})
+		if err != nil {
+			t.Errorf("SearchFile() error = %v", err)
+		}
+	})
+
+	t.Run("unsized", func(t *testing.T) {
+		// Files of /proc report a size of zero but are not empty.
+		const status = "/proc/self/status"
+		if _, err := os.Stat(status); err != nil {
+			t.Skip(err)
+		}
+
+		name := pcregexp.MustCompile(`(?m)^Name:`)
+		defer name.Close()
+
+		calls := 0
+		err := pcregexp.SearchFile(status, name, func(start, end int64, match []byte) bool {
+			calls++
+			return true
+		})
+		if err != nil {
+			t.Errorf("SearchFile() error = %v", err)
+		}
+
+		if calls != 1 {
+			t.Errorf("fn called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := pcregexp.SearchFile(filepath.Join(t.TempDir(), "missing"), re, func(start, end int64, match []byte) bool {
+			return true
+		})
+		if !os.IsNotExist(err) {
+			t.Errorf("SearchFile() error = %v, want not exist", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package pcregexp
+
+// Info describes a compiled pattern, mostly for debugging.
+type Info struct {
+	// Pattern is the source text of the pattern.
+	Pattern string
+
+	// Options holds the compile options of the pattern.
+	Options Option
+
+	// NumSubexp is the number of parenthesized subexpressions.
+	NumSubexp int
+
+	// MinLength is a lower bound on the length of a match, in characters.
+	MinLength int
+
+	// FirstCodeUnit is the byte every match starts with, or -1 if there is
+	// none.
+	FirstCodeUnit int
+
+	// LastCodeUnit is the rightmost byte every match contains other than at
+	// its start, or -1 if there is none.
+	LastCodeUnit int
+
+	// Prefilter is a literal that every match contains. Subjects that do not
+	// contain it, or are shorter than MinLength, are rejected without calling
+	// PCRE2. It is empty if the pattern has no such literal.
+	Prefilter string
+
+	// Literal reports whether the pattern matches Prefilter and nothing else,
+	// in which case PCRE2 is not called at all.
+	Literal bool
+}
+
+// Info returns information about the compiled pattern, including the prefilter
+// checked before calling PCRE2.
+func (re *PCREgexp) Info() Info {
+	info := Info{
+		Pattern:       re.pattern,
+		Options:       re.opts,
+		FirstCodeUnit: -1,
+		LastCodeUnit:  -1,
+	}
+
+	if re.code == 0 {
+		return info
+	}
+
+	info.NumSubexp = re.NumSubexp()
+	info.MinLength = int(re.info(pcre2InfoMinLength))
+
+	if re.info(pcre2InfoFirstCodeType) == 1 {
+		info.FirstCodeUnit = int(re.info(pcre2InfoFirstCodeUnit))
+	}
+
+	if re.info(pcre2InfoLastCodeType) == 1 {
+		info.LastCodeUnit = int(re.info(pcre2InfoLastCodeUnit))
+	}
+
+	if re.pre != nil {
+		info.Prefilter = string(re.pre.literal)
+		info.Literal = re.pre.complete
+	}
+
+	return info
+}
+
+// info returns a 32-bit item of information about the compiled pattern, or 0.
+func (re *PCREgexp) info(what uint32) uint32 {
+	var v uint32
+	if pcre2_pattern_info(re.code, what, ptr(&v)) != 0 {
+		return 0
+	}
+
+	return v
+}
@@ -0,0 +1,461 @@
+package pcregexp
+
+import (
+	"bytes"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// emptySubject stands in for the data of empty subjects, as PCRE2 does not
+// accept a NULL subject.
+var emptySubject byte
+
+// matchData is a pcre2_match_data block, along with what is needed to run
+// pcre2_match on it without allocating.
+type matchData struct {
+	handle uintptr // pcre2_match_data
+
+	// ovector is the output vector of handle, which never moves. PCRE2_SIZE
+	// is as wide as int, and PCRE2_UNSET reads as -1.
+	ovector []int
+
+	// call passes the arguments of pcre2_match.
+	call nativeCall
+
+	// subject keeps the subject of the running match reachable, and on the
+	// heap, while C only sees its address in call.
+	subject ptr
+
+	// context is the match context of the matches run on the block, with
+	// the callout function of the regexp when the block was taken.
+	context matchContext
+}
+
+// matchContext is a pcre2_match_context used by one match at a time, created
+// on demand for matches calling a callout function or limited to an offset.
+type matchContext struct {
+	handle     uintptr
+	limit      int            // offset limit handle is set up with, or -1
+	callout    calloutHandler // callout_data of handle
+	calloutSet bool           // whether handle calls the callout function
+}
+
+// get returns the match context for a match calling c.callout.fn, if set, and
+// starting no later than offset limit, if limit >= 0. It returns 0 if neither
+// applies, and false if the context cannot be created.
+func (c *matchContext) get(gctx uintptr, limit int) (uintptr, bool) {
+	if c.callout.fn == nil && limit < 0 {
+		return 0, true
+	}
+
+	if c.handle == 0 {
+		c.handle = pcre2_match_context_create(gctx)
+		if c.handle == 0 {
+			return 0, false
+		}
+
+		c.limit = -1
+	}
+
+	if set := c.callout.fn != nil; set != c.calloutSet {
+		if !set {
+			pcre2_set_callout(c.handle, 0, 0)
+		} else {
+			if c.callout.handle == 0 {
+				c.callout.handle = newHandle(&c.callout)
+			}
+			pcre2_set_callout(c.handle, calloutFunction(), c.callout.handle)
+		}
+		c.calloutSet = set
+	}
+
+	if c.limit != limit {
+		// A limit of -1 converts to PCRE2_UNSET, which removes it.
+		pcre2_set_offset_limit(c.handle, uint64(limit))
+		c.limit = limit
+	}
+
+	return c.handle, true
+}
+
+// free releases the match context.
+func (c *matchContext) free() {
+	if c.handle != 0 {
+		pcre2_match_context_free(c.handle)
+		c.handle = 0
+	}
+
+	if c.callout.handle != 0 {
+		deleteHandle(c.callout.handle)
+		c.callout.handle = 0
+	}
+	c.calloutSet = false
+}
+
+// free releases the match data block and its match context.
+func (m *matchData) free() {
+	pcre2_match_data_free(m.handle)
+	m.context.free()
+}
+
+// newMatchData creates a match data block with room for every capture group.
+func (re *PCREgexp) newMatchData() *matchData {
+	md := pcre2_match_data_create_from_pattern(re.code, re.mem.gctx)
+	if md == 0 {
+		return nil
+	}
+
+	n := pcre2_get_ovector_count(md)
+
+	return &matchData{
+		handle:  md,
+		ovector: unsafe.Slice((*int)(ptr(pcre2_get_ovector_pointer(md))), 2*n),
+	}
+}
+
+// exec runs pcre2_match on subject from byte offset start. It returns the
+// number of offset pairs set in m.ovector, or a negative PCRE2 error code.
+//
+// Unless there is a callout to observe the match, the prefilter of the regexp
+// is checked first, and PCRE2 is not called for literal patterns.
+func (re *PCREgexp) exec(m *matchData, subject []byte, start int, options uint32) int {
+	return re.execLimit(m, subject, start, -1, options)
+}
+
+// execLimit is exec for matches starting no later than byte offset limit, or
+// anywhere if limit < 0.
+func (re *PCREgexp) execLimit(m *matchData, subject []byte, start, limit int, options uint32) int {
+	if re.pre != nil && options&^pcre2NoUTFCheck == 0 && m.context.callout.fn == nil {
+		if re.pre.complete {
+			i := bytes.Index(subject[start:], re.pre.literal)
+			if i < 0 || (limit >= 0 && start+i > limit) {
+				return int(pcre2ErrorNoMatch)
+			}
+
+			m.ovector[0], m.ovector[1] = start+i, start+i+len(re.pre.literal)
+
+			return 1
+		}
+
+		if re.pre.rejects(subject[start:]) {
+			return int(pcre2ErrorNoMatch)
+		}
+	}
+
+	// Without UseOffsetLimit, PCRE2 refuses an offset limit.
+	contextLimit := -1
+	if re.opts&UseOffsetLimit != 0 {
+		contextLimit = limit
+	}
+
+	mcontext, ok := m.context.get(re.mem.gctx, contextLimit)
+	if !ok {
+		return int(pcre2ErrorNoMatch)
+	}
+
+	rc := re.native(m, subject, start, options, mcontext)
+
+	// Without UseOffsetLimit, PCRE2 may find a match starting past the
+	// limit, in which case there is none before it.
+	if rc >= 0 && limit >= 0 && m.ovector[0] > limit {
+		return int(pcre2ErrorNoMatch)
+	}
+
+	return rc
+}
+
+// native is exec without the Go-side checks: it always calls pcre2_match,
+// with the given match context.
+func (re *PCREgexp) native(m *matchData, subject []byte, start int, options uint32, mcontext uintptr) int {
+	m.subject = ptr(&emptySubject)
+	if len(subject) > 0 {
+		m.subject = ptr(&subject[0])
+	}
+
+	rc := m.call.call7(pcre2_match, re.code, uintptr(m.subject), uintptr(len(subject)),
+		uintptr(start), uintptr(options), m.handle, mcontext)
+	m.subject = nil
+
+	m.context.callout.repanic()
+
+	return int(int32(rc))
+}
+
+// doMatch appends to dst the offsets of the first pairs offset pairs (all of
+// them if pairs < 0) of the leftmost match in b, and reports whether there was
+// a match.
+func (re *PCREgexp) doMatch(dst []int, b []byte, pairs int) ([]int, bool) {
+	if re.code == 0 {
+		return dst, false
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return dst, false
+	}
+	defer re.putMatchData(m)
+
+	if re.exec(m, b, 0, 0) < 0 {
+		return dst, false
+	}
+
+	return append(dst, m.pairs(pairs)...), true
+}
+
+// pairs returns the first n offset pairs of the ovector, or all of them if
+// n < 0.
+func (m *matchData) pairs(n int) []int {
+	if n < 0 || 2*n > len(m.ovector) {
+		return m.ovector
+	}
+
+	return m.ovector[:2*n]
+}
+
+// allMatches calls deliver with the offsets of up to n successive,
+// non-overlapping matches in b, or all of them if n < 0. The offsets are only
+// valid during the call.
+//
+// As with package regexp, an empty match right after a previous match is
+// ignored. The search resumes one character after an empty match: one byte
+// with [NeverUTF] or in the middle of an invalid UTF-8 sequence, and one rune
+// otherwise.
+func (re *PCREgexp) allMatches(b []byte, n int, deliver func(match []int)) {
+	if re.code == 0 || n == 0 {
+		return
+	}
+
+	re.eachMatch(b, n, func(match []int) bool {
+		deliver(match)
+		return true
+	})
+}
+
+// eachMatch is allMatches, but stops early once deliver returns false.
+func (re *PCREgexp) eachMatch(b []byte, n int, deliver func(match []int) bool) {
+	if re.code == 0 || n == 0 {
+		return
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return
+	}
+	defer re.putMatchData(m)
+
+	re.scan(m, b, n, deliver)
+}
+
+// scan is allMatches using the match data m. It stops early once deliver
+// returns false.
+func (re *PCREgexp) scan(m *matchData, b []byte, n int, deliver func(match []int) bool) {
+	st := scanState{prevEnd: -1}
+	re.scanFrom(m, b, n, &st, 0, deliver)
+}
+
+// scanState is where successive searches resume: the offset to search from,
+// and the end of the previous match, or -1 if there is none.
+type scanState struct {
+	pos, prevEnd int
+}
+
+// scanFrom is scan resuming from st, which it keeps up to date: when deliver
+// is called, st is where the search for the next match resumes. The first
+// search is run with the given match options.
+func (re *PCREgexp) scanFrom(m *matchData, b []byte, n int, st *scanState, options uint32, deliver func(match []int) bool) {
+	for i := 0; (n < 0 || i < n) && st.pos <= len(b); {
+		if re.exec(m, b, st.pos, options) < 0 {
+			break
+		}
+
+		// The subject was checked to be valid UTF once; there is no need
+		// to check it again for every match.
+		options = re.utfChecked()
+
+		match := m.ovector
+		accept := accepts(st.pos, st.prevEnd, match)
+		st.pos, st.prevEnd = re.nextPos(b, st.pos, match), match[1]
+
+		if accept {
+			if !deliver(match) {
+				break
+			}
+			i++
+		}
+	}
+}
+
+// utfChecked returns the match options to use on a subject already checked to
+// be valid UTF.
+func (re *PCREgexp) utfChecked() uint32 {
+	// Subjects are never checked as a whole with MatchInvalidUTF.
+	if re.opts.strictUTF() {
+		return pcre2NoUTFCheck
+	}
+
+	return 0
+}
+
+// accepts reports whether match, found searching from pos, is one of the
+// successive matches, given the end of the previous match. An empty match
+// right after a previous match is not.
+func accepts(pos, prevEnd int, match []int) bool {
+	return match[1] > pos || match[0] != prevEnd
+}
+
+// nextPos returns the offset from which to search for the match following
+// match, found searching b from pos. The search steps over a character after
+// an empty match so that it advances.
+func (re *PCREgexp) nextPos(b []byte, pos int, match []int) int {
+	if match[1] > pos {
+		return match[1]
+	}
+
+	return re.step(b, pos)
+}
+
+// step returns the offset of the character following the one at pos in b:
+// one byte later with NeverUTF, or one rune later otherwise, as in package
+// regexp, so that empty matches do not split runes. It returns len(b)+1 at
+// the end of b.
+func (re *PCREgexp) step(b []byte, pos int) int {
+	if pos >= len(b) {
+		return len(b) + 1
+	}
+
+	if re.opts&NeverUTF != 0 {
+		return pos + 1
+	}
+
+	_, width := utf8.DecodeRune(b[pos:])
+
+	return pos + width
+}
+
+// appendAll appends to dst the first pairs offset pairs (all of them if
+// pairs < 0) of up to n successive matches in b. It also returns the number
+// of offsets appended per match.
+func (re *PCREgexp) appendAll(dst []int, b []byte, n, pairs int) ([]int, int) {
+	stride := 0
+
+	re.allMatches(b, n, func(match []int) {
+		if pairs >= 0 && 2*pairs < len(match) {
+			match = match[:2*pairs]
+		}
+
+		stride = len(match)
+		dst = append(dst, match...)
+	})
+
+	return dst, stride
+}
+
+// chunk cuts offsets returned by appendAll into one slice per match, sharing
+// their backing array. It returns nil if there are none.
+func chunk(offsets []int, stride int) [][]int {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	chunks := make([][]int, len(offsets)/stride)
+	for i := range chunks {
+		chunks[i] = offsets[i*stride : (i+1)*stride : (i+1)*stride]
+	}
+
+	return chunks
+}
+
+// replaceAll appends src to dst, with every match of the regexp replaced by
+// what repl appends for it.
+func (re *PCREgexp) replaceAll(dst, src []byte, repl func(dst []byte, match []int) []byte) []byte {
+	return re.replace(dst, src, -1, func(dst []byte, match []int) ([]byte, bool) {
+		return repl(dst, match), true
+	})
+}
+
+// replace appends src to dst, with up to n successive matches of the regexp
+// (all of them if n < 0) replaced by what repl appends for them. Once repl
+// reports false, the search stops and the rest of src is appended as is.
+func (re *PCREgexp) replace(dst, src []byte, n int, repl func(dst []byte, match []int) ([]byte, bool)) []byte {
+	if re.code == 0 || n == 0 {
+		return append(dst, src...)
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return append(dst, src...)
+	}
+	defer re.putMatchData(m)
+
+	return re.replaceWith(m, dst, src, n, repl)
+}
+
+// replaceWith is replace using the match data m, which repl may inspect.
+func (re *PCREgexp) replaceWith(m *matchData, dst, src []byte, n int, repl func(dst []byte, match []int) ([]byte, bool)) []byte {
+	last := 0
+
+	re.scan(m, src, n, func(match []int) bool {
+		if match[0] > last {
+			dst = append(dst, src[last:match[0]]...)
+			last = match[0]
+		}
+
+		var ok bool
+		if dst, ok = repl(dst, match); !ok {
+			return false
+		}
+
+		if match[1] > last {
+			last = match[1]
+		}
+
+		return true
+	})
+
+	return append(dst, src[last:]...)
+}
+
+// AppendFindIndex appends the start and end offsets of the leftmost match of
+// the regexp in b to dst and returns the result. If there is no match, dst is
+// returned unchanged.
+//
+// Unlike [PCREgexp.FindIndex], it does not allocate when dst has room for the
+// offsets, which makes it suitable for hot loops.
+func (re *PCREgexp) AppendFindIndex(dst []int, b []byte) []int {
+	dst, _ = re.doMatch(dst, b, 1)
+	return dst
+}
+
+// AppendFindStringIndex is like [PCREgexp.AppendFindIndex] but searches s.
+func (re *PCREgexp) AppendFindStringIndex(dst []int, s string) []int {
+	return re.AppendFindIndex(dst, stringToBytesUnsafe(s))
+}
+
+// AppendFindSubmatchIndex appends the index pairs of the leftmost match of
+// the regexp in b and of its subexpressions to dst, as returned by
+// [PCREgexp.FindSubmatchIndex], and returns the result. If there is no match,
+// dst is returned unchanged.
+func (re *PCREgexp) AppendFindSubmatchIndex(dst []int, b []byte) []int {
+	dst, _ = re.doMatch(dst, b, -1)
+	return dst
+}
+
+// AppendFindStringSubmatchIndex is like [PCREgexp.AppendFindSubmatchIndex]
+// but searches s.
+func (re *PCREgexp) AppendFindStringSubmatchIndex(dst []int, s string) []int {
+	return re.AppendFindSubmatchIndex(dst, stringToBytesUnsafe(s))
+}
+
+// AppendFindAllIndex appends the start and end offsets of up to n successive
+// matches of the regexp in b to dst, one pair after the other, and returns the
+// result. If n < 0, all matches are appended.
+func (re *PCREgexp) AppendFindAllIndex(dst []int, b []byte, n int) []int {
+	dst, _ = re.appendAll(dst, b, n, 1)
+	return dst
+}
+
+// AppendFindAllStringIndex is like [PCREgexp.AppendFindAllIndex] but searches
+// s.
+func (re *PCREgexp) AppendFindAllStringIndex(dst []int, s string, n int) []int {
+	return re.AppendFindAllIndex(dst, stringToBytesUnsafe(s), n)
+}
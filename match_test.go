@@ -0,0 +1,91 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestAppendFindIndex(t *testing.T) {
+	re := pcregexp.MustCompile(`p([a-z]+)ch`)
+	defer re.Close()
+
+	dst := []int{-7}
+
+	if got, want := re.AppendFindIndex(dst, []byte("a peach")), []int{-7, 2, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendFindIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := re.AppendFindStringSubmatchIndex(dst, "a peach"), []int{-7, 2, 7, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendFindStringSubmatchIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := re.AppendFindAllStringIndex(dst, "peach punch", -1), []int{-7, 0, 5, 6, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendFindAllStringIndex() = %v, want %v", got, want)
+	}
+
+	if got := re.AppendFindStringIndex(dst, "apple"); !reflect.DeepEqual(got, dst) {
+		t.Errorf("AppendFindStringIndex() = %v, want %v unchanged", got, dst)
+	}
+}
+
+// TestAllMatches checks that successive matches, empty ones in particular,
+// are found as by package regexp.
+func TestAllMatches(t *testing.T) {
+	patterns := []string{`a*`, `x*`, `\b`, `(a)(b)?`, `^`, `$`, ``}
+	subjects := []string{"", "baaac", "abab", "ab ab"}
+
+	for _, pattern := range patterns {
+		re := pcregexp.MustCompile(pattern)
+		defer re.Close()
+		std := regexp.MustCompile(pattern)
+
+		for _, s := range subjects {
+			if got, want := re.FindAllStringSubmatchIndex(s, -1), std.FindAllStringSubmatchIndex(s, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q.FindAllStringSubmatchIndex(%q) = %v, want %v", pattern, s, got, want)
+			}
+
+			if got, want := re.ReplaceAllString(s, "-"), std.ReplaceAllLiteralString(s, "-"); got != want {
+				t.Errorf("%q.ReplaceAllString(%q) = %q, want %q", pattern, s, got, want)
+			}
+
+			if got, want := re.Split(s, -1), std.Split(s, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q.Split(%q) = %q, want %q", pattern, s, got, want)
+			}
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		max     int
+		want    int
+	}{
+		{`p[a-z]+ch`, "peach punch pinch", -1, 3},
+		{`p[a-z]+ch`, "peach punch pinch", 2, 2},
+		{`p[a-z]+ch`, "peach punch pinch", 0, 0},
+		{`punch`, "punch punch", -1, 2},
+		{`x*`, "abc", -1, 4},
+		{`x`, "abc", -1, 0},
+	}
+
+	for _, tt := range tests {
+		re := pcregexp.MustCompile(tt.pattern)
+
+		if got := re.CountUpTo([]byte(tt.subject), tt.max); got != tt.want {
+			t.Errorf("%q: CountUpTo(%d) = %d, want %d", tt.pattern, tt.max, got, tt.want)
+		}
+
+		if tt.max < 0 {
+			if got, want := re.CountString(tt.subject), len(re.FindAllStringIndex(tt.subject, -1)); got != want {
+				t.Errorf("%q: CountString() = %d, want %d", tt.pattern, got, want)
+			}
+		}
+
+		re.Close()
+	}
+}
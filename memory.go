@@ -0,0 +1,327 @@
+package pcregexp
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// ErrMemoryLimit is returned when an operation would take the native memory
+// allocated by PCRE2 beyond the limit set with [SetMemoryLimit].
+var ErrMemoryLimit = errors.New("native memory limit exceeded")
+
+// MemoryStats describes the native memory allocated by PCRE2.
+type MemoryStats struct {
+	// InUse is the number of bytes currently allocated.
+	InUse int64
+
+	// TotalAlloc is the cumulative number of bytes allocated.
+	TotalAlloc int64
+
+	// Mallocs is the cumulative number of allocations.
+	Mallocs int64
+
+	// Frees is the cumulative number of allocations freed.
+	Frees int64
+
+	// Refused is the cumulative number of allocations refused because of
+	// the limit set with [SetMemoryLimit].
+	Refused int64
+
+	// Limit is the limit set with [SetMemoryLimit], or 0.
+	Limit int64
+
+	// Accounted reports whether native memory is accounted at all. It is
+	// false if the C library could not be loaded, in which case PCRE2
+	// allocates on its own, the other statistics stay zero and
+	// [SetMemoryLimit] has no effect.
+	Accounted bool
+}
+
+// memHeader is the room kept before every allocation to remember its size.
+// It also preserves the 16-byte alignment of malloc.
+const memHeader = 16
+
+var (
+	libc_malloc func(size uint64) ptr
+	libc_free   func(p ptr)
+
+	// memAccounting reports whether libc_malloc and libc_free were loaded.
+	memAccounting bool
+)
+
+var (
+	// globalMem accounts for every allocation made by PCRE2.
+	globalMem memAccount
+
+	// memLimit is the ceiling set with SetMemoryLimit, 0 if none.
+	memLimit int64
+
+	memOnce       sync.Once
+	mallocFuncPtr uintptr
+	freeFuncPtr   uintptr
+)
+
+func init() {
+	var libNames []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		libNames = []string{"/usr/lib/libSystem.B.dylib"}
+	case "linux":
+		libNames = []string{"libc.so.6", "libc.so"} // glibc, then musl
+	case "freebsd":
+		libNames = []string{"libc.so.7"}
+	case "windows":
+		libNames = []string{"msvcrt.dll"}
+	}
+
+	// Accounting is optional: without the C library, general contexts are
+	// left to PCRE2's own allocator.
+	for _, name := range libNames {
+		lib, err := openLibrary(name)
+		if err != nil {
+			continue
+		}
+
+		purego.RegisterLibFunc(&libc_malloc, lib, "malloc")
+		purego.RegisterLibFunc(&libc_free, lib, "free")
+		memAccounting = true
+
+		return
+	}
+}
+
+// memAccount tracks the memory PCRE2 allocates through one general context,
+// and is the memory_data of that context.
+//
+// An account outlives the regexp that created it for as long as compiled code
+// allocated through it may still be freed, so it is reference counted.
+type memAccount struct {
+	inUse   int64
+	total   int64
+	mallocs int64
+	frees   int64
+	refused int64
+
+	refs   int32
+	handle uintptr // memory_data of gctx
+	gctx   uintptr // pcre2_general_context allocating through the account, or 0
+}
+
+// memFunctions returns the C function pointers of the private malloc and free
+// given to pcre2_general_context_create.
+func memFunctions() (uintptr, uintptr) {
+	memOnce.Do(func() {
+		mallocFuncPtr = purego.NewCallback(memMalloc)
+		freeFuncPtr = purego.NewCallback(memFree)
+	})
+
+	return mallocFuncPtr, freeFuncPtr
+}
+
+// newMemAccount returns an account with refs references and a general
+// context charging it. Without accounting, the account has no general
+// context, and PCRE2 uses its default one.
+func newMemAccount(refs int32) (*memAccount, error) {
+	a := &memAccount{refs: refs}
+	if !memAccounting {
+		return a, nil
+	}
+
+	a.handle = newHandle(a)
+
+	malloc, free := memFunctions()
+
+	a.gctx = pcre2_general_context_create(malloc, free, a.handle)
+	if a.gctx == 0 {
+		deleteHandle(a.handle)
+		return nil, memoryError("pcre2_general_context_create")
+	}
+
+	return a, nil
+}
+
+// memoryError returns the error of a PCRE2 function that could not allocate
+// memory: ErrMemoryLimit if a limit is set, which is most likely the cause.
+func memoryError(function string) error {
+	if atomic.LoadInt64(&memLimit) > 0 {
+		return ErrMemoryLimit
+	}
+
+	return fmt.Errorf("%s failed", function)
+}
+
+// release drops a reference to the account, freeing its general context once
+// the last one is gone. Everything allocated through the account must have
+// been freed by then.
+func (a *memAccount) release() {
+	if a == nil || atomic.AddInt32(&a.refs, -1) != 0 {
+		return
+	}
+
+	if a.gctx != 0 {
+		pcre2_general_context_free(a.gctx)
+		deleteHandle(a.handle)
+	}
+}
+
+// stats returns a snapshot of the account.
+func (a *memAccount) stats() MemoryStats {
+	if a == nil {
+		return MemoryStats{}
+	}
+
+	return MemoryStats{
+		InUse:      atomic.LoadInt64(&a.inUse),
+		TotalAlloc: atomic.LoadInt64(&a.total),
+		Mallocs:    atomic.LoadInt64(&a.mallocs),
+		Frees:      atomic.LoadInt64(&a.frees),
+		Refused:    atomic.LoadInt64(&a.refused),
+		Limit:      atomic.LoadInt64(&memLimit),
+		Accounted:  memAccounting,
+	}
+}
+
+// memMalloc is the private malloc of general contexts; data is the handle of
+// the account to charge.
+func memMalloc(size uint64, data uintptr) ptr {
+	n := int64(size)
+
+	limit := atomic.LoadInt64(&memLimit)
+	a, _ := loadHandle(data).(*memAccount)
+
+	if inUse := atomic.AddInt64(&globalMem.inUse, n); limit > 0 && inUse > limit {
+		atomic.AddInt64(&globalMem.inUse, -n)
+		atomic.AddInt64(&globalMem.refused, 1)
+		if a != nil {
+			atomic.AddInt64(&a.refused, 1)
+		}
+
+		return nil
+	}
+
+	p := libc_malloc(size + memHeader)
+	if p == nil {
+		atomic.AddInt64(&globalMem.inUse, -n)
+		return nil
+	}
+	*(*uint64)(p) = size
+
+	atomic.AddInt64(&globalMem.total, n)
+	atomic.AddInt64(&globalMem.mallocs, 1)
+
+	if a != nil {
+		atomic.AddInt64(&a.inUse, n)
+		atomic.AddInt64(&a.total, n)
+		atomic.AddInt64(&a.mallocs, 1)
+	}
+
+	return unsafe.Add(p, memHeader)
+}
+
+// memFree is the private free of general contexts.
+func memFree(p ptr, data uintptr) uintptr {
+	if p == nil {
+		return 0
+	}
+
+	base := unsafe.Add(p, -memHeader)
+	n := int64(*(*uint64)(base))
+
+	atomic.AddInt64(&globalMem.inUse, -n)
+	atomic.AddInt64(&globalMem.frees, 1)
+
+	if a, _ := loadHandle(data).(*memAccount); a != nil {
+		atomic.AddInt64(&a.inUse, -n)
+		atomic.AddInt64(&a.frees, 1)
+	}
+
+	libc_free(base)
+
+	return 0
+}
+
+// MemStats returns statistics about the native memory allocated by PCRE2 for
+// all regexps, which the Go runtime does not see.
+func MemStats() MemoryStats {
+	return globalMem.stats()
+}
+
+// SetMemoryLimit sets a ceiling, in bytes, on the native memory PCRE2 may
+// allocate for all regexps together. Once reached, compiling fails with
+// [ErrMemoryLimit]. Matches that need more memory fail as if the subject did
+// not match, except for [PCREgexp.MatchErr], which returns ErrMemoryLimit;
+// [MemoryStats] counts the allocations refused. A limit of zero or less
+// removes the ceiling.
+func SetMemoryLimit(n int64) {
+	if n < 0 {
+		n = 0
+	}
+
+	atomic.StoreInt64(&memLimit, n)
+}
+
+// MemStats returns statistics about the native memory allocated by PCRE2 for
+// the compiled pattern and its matches.
+//
+// Handles returned by [Cached] share the statistics of their compiled code,
+// and so do patterns restored together by [DeserializePatterns].
+func (re *PCREgexp) MemStats() MemoryStats {
+	return re.mem.stats()
+}
+
+// MatchErr is like [PCREgexp.Match], but reports the errors Match takes for no
+// match: [ErrMemoryLimit] if PCRE2 needed more native memory than the limit
+// set with [SetMemoryLimit] allows, a *[UTFError] for subjects that are not
+// valid UTF-8 in strict UTF mode, as [PCREgexp.Validate] would return, or the
+// error code of pcre2_match for its other failures.
+func (re *PCREgexp) MatchErr(b []byte) (bool, error) {
+	if re.code == 0 {
+		return false, fmt.Errorf("MatchErr called on a closed regexp")
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return false, memoryError("pcre2_match_data_create_from_pattern")
+	}
+	defer re.putMatchData(m)
+
+	var rc int32
+	if re.opts.strictUTF() {
+		// The prefilter would reject invalid subjects as not matching,
+		// without PCRE2 checking them.
+		mcontext, ok := m.context.get(re.mem.gctx, -1)
+		if !ok {
+			return false, memoryError("pcre2_match_context_create")
+		}
+		rc = int32(re.native(m, b, 0, 0, mcontext))
+	} else {
+		rc = int32(re.exec(m, b, 0, 0))
+	}
+
+	switch {
+	case rc >= 0:
+		return true, nil
+	case rc == pcre2ErrorNoMatch:
+		return false, nil
+	case rc == pcre2ErrorNoMemory:
+		return false, memoryError("pcre2_match")
+	}
+
+	if err := utfError(m, rc); err != nil {
+		return false, err
+	}
+
+	return false, fmt.Errorf("pcre2_match failed, error code %d", rc)
+}
+
+// MatchStringErr is like [PCREgexp.MatchErr] but matches s.
+func (re *PCREgexp) MatchStringErr(s string) (bool, error) {
+	return re.MatchErr(stringToBytesUnsafe(s))
+}
@@ -0,0 +1,115 @@
+package pcregexp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+// skipUnaccounted skips tests of native memory accounting where the C
+// library could not be loaded.
+func skipUnaccounted(t *testing.T) {
+	if !pcregexp.MemStats().Accounted {
+		t.Skip("native memory is not accounted")
+	}
+}
+
+func TestMemStats(t *testing.T) {
+	skipUnaccounted(t)
+
+	before := pcregexp.MemStats()
+
+	re := pcregexp.MustCompile(`p([a-z]+)ch`)
+	if !re.MatchString("peach") {
+		t.Fatalf("MatchString(%q) = false, want true", "peach")
+	}
+
+	stats := re.MemStats()
+	if stats.InUse <= 0 || stats.Mallocs <= 0 || stats.TotalAlloc < stats.InUse {
+		t.Errorf("MemStats() = %+v, want memory in use", stats)
+	}
+
+	global := pcregexp.MemStats()
+	if global.InUse-before.InUse != stats.InUse {
+		t.Errorf("global InUse grew by %d, want %d", global.InUse-before.InUse, stats.InUse)
+	}
+
+	re.Close()
+
+	after := pcregexp.MemStats()
+	if after.InUse != before.InUse {
+		t.Errorf("InUse after Close() = %d, want %d", after.InUse, before.InUse)
+	}
+	if after.Frees-before.Frees != after.Mallocs-before.Mallocs {
+		t.Errorf("Frees grew by %d, want %d", after.Frees-before.Frees, after.Mallocs-before.Mallocs)
+	}
+}
+
+func TestSetMemoryLimit(t *testing.T) {
+	skipUnaccounted(t)
+	defer pcregexp.SetMemoryLimit(0)
+
+	pcregexp.SetMemoryLimit(pcregexp.MemStats().InUse + 1)
+
+	if got := pcregexp.MemStats().Limit; got == 0 {
+		t.Errorf("MemStats().Limit = 0, want the limit")
+	}
+
+	_, err := pcregexp.Compile(`p([a-z]+)ch`)
+	if !errors.Is(err, pcregexp.ErrMemoryLimit) {
+		t.Errorf("Compile() error = %v, want %v", err, pcregexp.ErrMemoryLimit)
+	}
+
+	pcregexp.SetMemoryLimit(0)
+
+	re, err := pcregexp.Compile(`p([a-z]+)ch`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	defer re.Close()
+
+	if !re.MatchString("peach") {
+		t.Errorf("MatchString(%q) = false, want true", "peach")
+	}
+}
+
+func TestMatchErr(t *testing.T) {
+	skipUnaccounted(t)
+	defer pcregexp.SetMemoryLimit(0)
+
+	re := pcregexp.MustCompile(`(a|b)*c`)
+	defer re.Close()
+
+	// Create the match data, and its first backtracking frames, unlimited.
+	if ok, err := re.MatchStringErr("ac"); !ok || err != nil {
+		t.Fatalf("MatchStringErr() = %v, %v, want true, nil", ok, err)
+	}
+
+	before := re.MemStats()
+	pcregexp.SetMemoryLimit(pcregexp.MemStats().InUse + 1)
+
+	// Every iteration of the group takes a frame, well beyond the first ones.
+	subject := strings.Repeat("a", 10000) + "c"
+
+	ok, err := re.MatchStringErr(subject)
+	if ok || !errors.Is(err, pcregexp.ErrMemoryLimit) {
+		t.Errorf("MatchStringErr() = %v, %v, want false, %v", ok, err, pcregexp.ErrMemoryLimit)
+	}
+	if re.MatchString(subject) {
+		t.Errorf("MatchString() = true, want false")
+	}
+	if refused := re.MemStats().Refused - before.Refused; refused < 2 {
+		t.Errorf("Refused grew by %d, want at least 2", refused)
+	}
+
+	pcregexp.SetMemoryLimit(0)
+
+	if ok, err := re.MatchStringErr(subject); !ok || err != nil {
+		t.Errorf("MatchStringErr() without limit = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := re.MatchStringErr("xyz"); ok || err != nil {
+		t.Errorf("MatchStringErr(%q) = %v, %v, want false, nil", "xyz", ok, err)
+	}
+}
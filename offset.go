@@ -0,0 +1,94 @@
+package pcregexp
+
+// findAt appends to dst the offsets of the first pairs offset pairs (all of
+// them if pairs < 0) of the leftmost match in b starting between start and
+// limit (anywhere after start if limit < 0), and reports whether there was a
+// match. It is anchored at start if anchored is set.
+func (re *PCREgexp) findAt(dst []int, b []byte, start, limit int, anchored bool, pairs int) ([]int, bool) {
+	if re.code == 0 || start < 0 || start > len(b) || (limit >= 0 && limit < start) {
+		return dst, false
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return dst, false
+	}
+	defer re.putMatchData(m)
+
+	var options uint32
+	if anchored {
+		options = pcre2Anchored
+	}
+
+	if re.execLimit(m, b, start, limit, options) < 0 {
+		return dst, false
+	}
+
+	return append(dst, m.pairs(pairs)...), true
+}
+
+// MatchAt reports whether the regexp matches b at or after byte offset start,
+// or exactly at start if anchored is set. Unlike matching b[start:], the text
+// before start is seen by lookbehind assertions, \b and the like, so that
+// lexers can match token after token in place.
+func (re *PCREgexp) MatchAt(b []byte, start int, anchored bool) bool {
+	_, ok := re.findAt(nil, b, start, -1, anchored, 0)
+	return ok
+}
+
+// MatchStringAt is like [PCREgexp.MatchAt] but matches s.
+func (re *PCREgexp) MatchStringAt(s string, start int, anchored bool) bool {
+	return re.MatchAt(stringToBytesUnsafe(s), start, anchored)
+}
+
+// FindIndexAt is like [PCREgexp.FindIndex] but searches b from byte offset
+// start, with the text before start seen by lookbehind assertions. The
+// offsets returned are relative to the start of b.
+func (re *PCREgexp) FindIndexAt(b []byte, start int) []int {
+	a, ok := re.findAt(nil, b, start, -1, false, 1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindStringIndexAt is like [PCREgexp.FindIndexAt] but searches s.
+func (re *PCREgexp) FindStringIndexAt(s string, start int) []int {
+	return re.FindIndexAt(stringToBytesUnsafe(s), start)
+}
+
+// FindSubmatchIndexAt is like [PCREgexp.FindIndexAt] but also returns the
+// index pairs of the subexpressions, as [PCREgexp.FindSubmatchIndex] does.
+func (re *PCREgexp) FindSubmatchIndexAt(b []byte, start int) []int {
+	a, ok := re.findAt(nil, b, start, -1, false, -1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindIndexWindow is like [PCREgexp.FindIndexAt] but only returns a match
+// starting no later than byte offset limit. The match itself may extend past
+// limit, and lookahead assertions see the rest of b.
+//
+// If the regexp was compiled with [UseOffsetLimit], PCRE2 stops searching at
+// limit; otherwise a match starting after it is found and then discarded.
+func (re *PCREgexp) FindIndexWindow(b []byte, start, limit int) []int {
+	if limit < 0 {
+		return nil
+	}
+
+	a, ok := re.findAt(nil, b, start, limit, false, 1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindStringIndexWindow is like [PCREgexp.FindIndexWindow] but searches s.
+func (re *PCREgexp) FindStringIndexWindow(s string, start, limit int) []int {
+	return re.FindIndexWindow(stringToBytesUnsafe(s), start, limit)
+}
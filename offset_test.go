@@ -0,0 +1,82 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestMatchAt(t *testing.T) {
+	re := pcregexp.MustCompile(`(?<=\$)\w+`)
+	defer re.Close()
+
+	s := "cost: $total"
+
+	// Slicing the subject would hide the "$" from the lookbehind.
+	if re.MatchString(s[7:]) {
+		t.Fatal("MatchString() = true on the slice, want false")
+	}
+
+	tests := []struct {
+		start    int
+		anchored bool
+		want     bool
+	}{
+		{7, true, true},
+		{6, true, false},
+		{0, false, true},
+		{9, true, false},
+		{len(s) + 1, false, false},
+		{-1, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := re.MatchStringAt(s, tt.start, tt.anchored); got != tt.want {
+			t.Errorf("MatchStringAt(%d, %v) = %v, want %v", tt.start, tt.anchored, got, tt.want)
+		}
+	}
+
+	if got, want := re.FindStringIndexAt(s, 3), []int{7, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindStringIndexAt() = %v, want %v", got, want)
+	}
+
+	if got := re.FindStringIndexAt(s, 8); got != nil {
+		t.Errorf("FindStringIndexAt() = %v, want nil", got)
+	}
+}
+
+func TestFindIndexWindow(t *testing.T) {
+	for _, opts := range []pcregexp.Option{0, pcregexp.UseOffsetLimit} {
+		for _, pattern := range []string{`needle`, `ne+dle`} {
+			re := pcregexp.MustCompileWithOptions(pattern, opts)
+
+			s := "hay needle hay needle"
+
+			tests := []struct {
+				start, limit int
+				want         []int
+			}{
+				{0, 4, []int{4, 10}},
+				{0, 3, nil},
+				{5, 14, nil},
+				{5, 15, []int{15, 21}},
+				{5, 100, []int{15, 21}},
+				{5, 4, nil},
+			}
+
+			for _, tt := range tests {
+				if got := re.FindStringIndexWindow(s, tt.start, tt.limit); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%q options %#x: FindStringIndexWindow(%d, %d) = %v, want %v", pattern, opts, tt.start, tt.limit, got, tt.want)
+				}
+			}
+
+			// Unlimited searches are unaffected by earlier windows.
+			if got, want := re.FindStringIndexAt(s, 5), []int{15, 21}; !reflect.DeepEqual(got, want) {
+				t.Errorf("%q options %#x: FindStringIndexAt() = %v, want %v", pattern, opts, got, want)
+			}
+
+			re.Close()
+		}
+	}
+}
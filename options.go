@@ -0,0 +1,79 @@
+package pcregexp
+
+// Option is a set of PCRE2 compile options, combined with the | operator and
+// passed to [CompileWithOptions].
+type Option uint32
+
+const (
+	// Caseless makes letters match both upper and lower case, like (?i).
+	Caseless Option = 0x00000008 // PCRE2_CASELESS
+
+	// DotAll makes a dot match any character including newline, like (?s).
+	DotAll Option = 0x00000020 // PCRE2_DOTALL
+
+	// DupNames allows names of capturing groups to be duplicated.
+	DupNames Option = 0x00000040 // PCRE2_DUPNAMES
+
+	// Extended ignores most white space and # comments in the pattern, like
+	// (?x).
+	Extended Option = 0x00000080 // PCRE2_EXTENDED
+
+	// Multiline makes ^ and $ match at newlines within the subject, like (?m).
+	Multiline Option = 0x00000400 // PCRE2_MULTILINE
+
+	// Ungreedy inverts the greediness of quantifiers, like (?U).
+	Ungreedy Option = 0x00040000 // PCRE2_UNGREEDY
+
+	// UCP uses Unicode properties for \d, \w, etc.
+	UCP Option = 0x00020000 // PCRE2_UCP
+
+	// UTF treats the pattern and subjects as UTF-8 strings. Subjects that are
+	// not valid UTF-8 never match; see [PCREgexp.Validate]. Without UTF or
+	// MatchInvalidUTF, patterns and subjects are strings of bytes, and \xHH
+	// matches a single byte, but iterating over matches still steps over a
+	// whole rune after an empty match, as package regexp does.
+	UTF Option = 0x00080000 // PCRE2_UTF
+
+	// NeverUTF is the raw byte mode: it forbids (*UTF) in the pattern, and
+	// iterating over matches steps one byte after an empty match, even in
+	// the middle of a valid UTF-8 sequence.
+	NeverUTF Option = 0x00001000 // PCRE2_NEVER_UTF
+
+	// MatchInvalidUTF implies UTF, but lets subjects hold invalid UTF-8
+	// sequences, which never match any part of the pattern. Matches are
+	// found around them.
+	MatchInvalidUTF Option = 0x04000000 // PCRE2_MATCH_INVALID_UTF
+
+	// AutoCallout inserts an automatic callout, numbered 255, before every item
+	// of the pattern. See [PCREgexp.SetCallout].
+	AutoCallout Option = 0x00000004 // PCRE2_AUTO_CALLOUT
+
+	// NoAutoPossess keeps PCRE2 from making quantifiers possessive where it
+	// makes no difference to the match, like (*NO_AUTO_POSSESS), so that
+	// callouts see the backtracking into them.
+	NoAutoPossess Option = 0x00004000 // PCRE2_NO_AUTO_POSSESS
+
+	// NoStartOptimize disables the optimizations that skip match attempts
+	// bound to fail, like (*NO_START_OPT), so that callouts see every
+	// attempt.
+	NoStartOptimize Option = 0x00010000 // PCRE2_NO_START_OPTIMIZE
+
+	// UseOffsetLimit lets PCRE2 give up as soon as no match can start before
+	// the limit passed to [PCREgexp.FindIndexWindow], rather than searching
+	// the rest of the subject.
+	UseOffsetLimit Option = 0x00800000 // PCRE2_USE_OFFSET_LIMIT
+
+	// Anchored forces matches to start at the first matching position.
+	Anchored Option = 0x80000000 // PCRE2_ANCHORED
+)
+
+// utf reports whether the options make the pattern and subjects UTF-8.
+func (o Option) utf() bool {
+	return o&(UTF|MatchInvalidUTF) != 0
+}
+
+// strictUTF reports whether subjects are checked to be valid UTF-8 before
+// being matched, which fails with a UTF-8 error code if they are not.
+func (o Option) strictUTF() bool {
+	return o&UTF != 0 && o&MatchInvalidUTF == 0
+}
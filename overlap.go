@@ -0,0 +1,201 @@
+package pcregexp
+
+import "unsafe"
+
+const (
+	// dfaMinPairs and dfaMaxPairs bound the number of matches
+	// pcre2_dfa_match reports at one start.
+	dfaMinPairs = 16
+	dfaMaxPairs = 1<<16 - 1
+
+	// dfaMinWorkspace and dfaMaxWorkspace bound the workspace of
+	// pcre2_dfa_match, in ints.
+	dfaMinWorkspace = 1000
+	dfaMaxWorkspace = 1 << 20
+)
+
+// overlapping calls deliver with the offsets of up to n matches in b, or all
+// of them if n < 0: the leftmost match, then the leftmost match starting at
+// least one character after it, and so on. The offsets are only valid during
+// the call.
+func (re *PCREgexp) overlapping(b []byte, n int, deliver func(match []int)) {
+	if re.code == 0 || n == 0 {
+		return
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return
+	}
+	defer re.putMatchData(m)
+
+	var options uint32
+
+	for pos, i := 0, 0; (n < 0 || i < n) && pos <= len(b); i++ {
+		if re.exec(m, b, pos, options) < 0 {
+			break
+		}
+		options = re.utfChecked()
+
+		deliver(m.ovector)
+		pos = re.step(b, m.ovector[0])
+	}
+}
+
+// FindAllOverlappingIndex is like [PCREgexp.FindAllIndex], but a match may
+// overlap the previous one: after each match, the search restarts one
+// character after its start rather than at its end. There is thus at most
+// one match starting at each offset, the one PCRE2 prefers there.
+func (re *PCREgexp) FindAllOverlappingIndex(b []byte, n int) [][]int {
+	var offsets []int
+
+	re.overlapping(b, n, func(match []int) {
+		offsets = append(offsets, match[:2]...)
+	})
+
+	return chunk(offsets, 2)
+}
+
+// FindAllStringOverlappingIndex is like [PCREgexp.FindAllOverlappingIndex]
+// but searches s.
+func (re *PCREgexp) FindAllStringOverlappingIndex(s string, n int) [][]int {
+	return re.FindAllOverlappingIndex(stringToBytesUnsafe(s), n)
+}
+
+// FindAllOverlapping returns the text of the matches found by
+// [PCREgexp.FindAllOverlappingIndex].
+func (re *PCREgexp) FindAllOverlapping(b []byte, n int) [][]byte {
+	var matches [][]byte
+
+	re.overlapping(b, n, func(match []int) {
+		matches = append(matches, b[match[0]:match[1]:match[1]])
+	})
+
+	return matches
+}
+
+// FindAllStringOverlapping returns the text of the matches found by
+// [PCREgexp.FindAllStringOverlappingIndex].
+func (re *PCREgexp) FindAllStringOverlapping(s string, n int) []string {
+	var matches []string
+
+	re.overlapping(stringToBytesUnsafe(s), n, func(match []int) {
+		matches = append(matches, s[match[0]:match[1]])
+	})
+
+	return matches
+}
+
+// FindAllOverlappingDFAIndex is like [PCREgexp.FindAllOverlappingIndex], but
+// reports every length a match may have at each start, longest first, as the
+// DFA algorithm of PCRE2 (pcre2_dfa_match) finds them. If n >= 0, at most n
+// index pairs are returned.
+//
+// The DFA algorithm does not support back references, conditions on them,
+// and a few other items; patterns holding them have no matches here. Note
+// that PCRE2 turns quantifiers into possessive ones where it makes no
+// difference to the leftmost match, as in a+ at the end of a pattern, which
+// leaves a single length; (*NO_AUTO_POSSESS) at the start of the pattern
+// prevents it.
+func (re *PCREgexp) FindAllOverlappingDFAIndex(b []byte, n int) [][]int {
+	if re.code == 0 || n == 0 {
+		return nil
+	}
+
+	d := &dfa{re: re, pairs: dfaMinPairs, workspace: make([]int32, dfaMinWorkspace)}
+	d.context.callout.fn = re.calloutFunc()
+	defer d.free()
+
+	var offsets []int
+	var options uint32
+
+	for pos := 0; (n < 0 || len(offsets) < 2*n) && pos <= len(b); {
+		pairs := d.match(b, pos, options)
+		if pairs == nil {
+			break
+		}
+		options = re.utfChecked()
+
+		if n >= 0 && len(offsets)+len(pairs) > 2*n {
+			pairs = pairs[:2*n-len(offsets)]
+		}
+
+		offsets = append(offsets, pairs...)
+		pos = re.step(b, pairs[0])
+	}
+
+	return chunk(offsets, 2)
+}
+
+// FindAllStringOverlappingDFAIndex is like
+// [PCREgexp.FindAllOverlappingDFAIndex] but searches s.
+func (re *PCREgexp) FindAllStringOverlappingDFAIndex(s string, n int) [][]int {
+	return re.FindAllOverlappingDFAIndex(stringToBytesUnsafe(s), n)
+}
+
+// dfa runs pcre2_dfa_match, growing its match data and workspace as needed.
+type dfa struct {
+	re        *PCREgexp
+	handle    uintptr // pcre2_match_data with room for pairs matches
+	pairs     int
+	workspace []int32
+	context   matchContext
+}
+
+// match returns the offset pairs of the matches at the leftmost start in b
+// from pos, longest first, or nil if there are none. They are only valid
+// until the next call.
+func (d *dfa) match(b []byte, pos int, options uint32) []int {
+	subject := &emptySubject
+	if len(b) > 0 {
+		subject = &b[0]
+	}
+
+	mcontext, ok := d.context.get(d.re.mem.gctx, -1)
+	if !ok {
+		return nil
+	}
+
+	for {
+		if d.handle == 0 {
+			d.handle = pcre2_match_data_create(uint32(d.pairs), d.re.mem.gctx)
+			if d.handle == 0 {
+				return nil
+			}
+		}
+
+		rc := pcre2_dfa_match(d.re.code, subject, uint64(len(b)), uint64(pos), options,
+			d.handle, mcontext, &d.workspace[0], uint64(len(d.workspace)))
+		d.context.callout.repanic()
+
+		switch {
+		case rc == 0 && d.pairs < dfaMaxPairs:
+			// There are more matches than room for them.
+			pcre2_match_data_free(d.handle)
+			d.handle = 0
+
+			if d.pairs *= 2; d.pairs > dfaMaxPairs {
+				d.pairs = dfaMaxPairs
+			}
+			continue
+		case rc == pcre2ErrorDFAWSSize && len(d.workspace) < dfaMaxWorkspace:
+			d.workspace = make([]int32, 2*len(d.workspace))
+			continue
+		case rc == 0:
+			rc = int32(d.pairs)
+		case rc < 0:
+			return nil
+		}
+
+		return unsafe.Slice((*int)(ptr(pcre2_get_ovector_pointer(d.handle))), 2*int(rc))
+	}
+}
+
+// free releases the match data and the match context.
+func (d *dfa) free() {
+	if d.handle != 0 {
+		pcre2_match_data_free(d.handle)
+		d.handle = 0
+	}
+	d.context.free()
+}
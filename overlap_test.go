@@ -0,0 +1,73 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestFindAllOverlappingIndex(t *testing.T) {
+	tests := []struct {
+		pattern string
+		opts    pcregexp.Option
+		subject string
+		n       int
+		want    [][]int
+	}{
+		{`ATA`, 0, "ATATAGATA", -1, [][]int{{0, 3}, {2, 5}, {6, 9}}},
+		{`A[TG]A`, 0, "ATATAGATA", 2, [][]int{{0, 3}, {2, 5}}},
+		{`(?=(..))`, 0, "abc", -1, [][]int{{0, 0}, {1, 1}}},
+		{`é.`, pcregexp.UTF, "ééé", -1, [][]int{{0, 4}, {2, 6}}},
+		{`x`, 0, "abc", -1, nil},
+	}
+
+	for _, tt := range tests {
+		re := pcregexp.MustCompileWithOptions(tt.pattern, tt.opts)
+
+		if got := re.FindAllStringOverlappingIndex(tt.subject, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: FindAllStringOverlappingIndex(%d) = %v, want %v", tt.pattern, tt.n, got, tt.want)
+		}
+
+		re.Close()
+	}
+
+	re := pcregexp.MustCompile(`AT?A`)
+	defer re.Close()
+
+	if got, want := re.FindAllStringOverlapping("AATA", -1), []string{"AA", "ATA"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllStringOverlapping() = %q, want %q", got, want)
+	}
+}
+
+func TestFindAllOverlappingDFAIndex(t *testing.T) {
+	re := pcregexp.MustCompile(`(*NO_AUTO_POSSESS)a+`)
+	defer re.Close()
+
+	got := re.FindAllStringOverlappingDFAIndex("xaaa", -1)
+	want := [][]int{{1, 4}, {1, 3}, {1, 2}, {2, 4}, {2, 3}, {3, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllStringOverlappingDFAIndex() = %v, want %v", got, want)
+	}
+
+	if got := re.FindAllStringOverlappingDFAIndex("xaaa", 4); !reflect.DeepEqual(got, want[:4]) {
+		t.Errorf("FindAllStringOverlappingDFAIndex(4) = %v, want %v", got, want[:4])
+	}
+
+	// More lengths at one start than the initial room for them.
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	if got := re.FindAllOverlappingDFAIndex(long, 1000); len(got) != 1000 || got[99][1] != 1 || got[100][0] != 1 {
+		t.Errorf("FindAllOverlappingDFAIndex() = %d pairs, %v at 99 and %v at 100", len(got), got[99], got[100])
+	}
+
+	backref := pcregexp.MustCompile(`(a)\1`)
+	defer backref.Close()
+
+	if got := backref.FindAllStringOverlappingDFAIndex("aaa", -1); got != nil {
+		t.Errorf("FindAllStringOverlappingDFAIndex() = %v, want nil for back references", got)
+	}
+}
@@ -0,0 +1,210 @@
+package pcregexp
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	// parallelMinChunk is the smallest part of a buffer searched on its own
+	// by FindAllIndexParallel.
+	parallelMinChunk = 64 << 10
+
+	// parallelChunksPerWorker is the number of parts of a buffer per worker,
+	// so that workers finishing early can take over the remaining parts.
+	parallelChunksPerWorker = 4
+)
+
+// searchStep is one search for a match in a buffer: the offset searched from,
+// and the offsets of the match found.
+type searchStep struct {
+	pos        int
+	start, end int // -1 if there was no match
+}
+
+// FindAllIndexParallel is like [PCREgexp.FindAllIndex], but splits b into
+// chunks searched concurrently by up to workers goroutines, or GOMAXPROCS
+// goroutines if workers <= 0. Buffers too small to be worth splitting are
+// searched sequentially.
+//
+// The result is exactly that of FindAllIndex. Each chunk is searched with the
+// whole of b as subject, starting at the chunk offset, so that lookbehind and
+// lookahead assertions see the text around the chunk and matches may extend
+// past its end. The searches are then replayed in order: where the matches
+// found in one chunk end at an offset its successor did not search from, for
+// instance in the middle of a match spanning the boundary, the search resumes
+// from there on the calling goroutine until both agree again.
+//
+// Callouts may be called more often, and in a different order, than by
+// FindAllIndex.
+func (re *PCREgexp) FindAllIndexParallel(b []byte, n, workers int) [][]int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	chunks := workers * parallelChunksPerWorker
+	if max := len(b) / parallelMinChunk; chunks > max {
+		chunks = max
+	}
+
+	if re.code == 0 || n == 0 || workers == 1 || chunks <= 1 {
+		return re.FindAllIndex(b, n)
+	}
+
+	bounds := chunkBounds(b, chunks)
+	traces := make([][]searchStep, len(bounds)-1)
+
+	re.parallel(workers, len(traces), 1, func(m *matchData, k int) {
+		traces[k] = re.trace(m, b, bounds[k], bounds[k+1], n)
+	})
+
+	m := re.getMatchData()
+	if m == nil {
+		return nil
+	}
+	defer re.putMatchData(m)
+
+	// Searches are replayed from the start of b, which the first search of
+	// the first chunk checked to be valid UTF if need be.
+	var offsets []int
+	pos, prevEnd := 0, -1
+
+	for k, trace := range traces {
+		for j := 0; pos < bounds[k+1] && (n < 0 || len(offsets) < 2*n); {
+			for j < len(trace) && trace[j].pos < pos {
+				j++
+			}
+
+			step := searchStep{pos: pos, start: -1, end: -1}
+			if j < len(trace) && trace[j].pos == pos {
+				step = trace[j]
+				j++
+			} else if re.exec(m, b, pos, re.utfChecked()) >= 0 {
+				step.start, step.end = m.ovector[0], m.ovector[1]
+			}
+
+			if step.start < 0 {
+				return chunk(offsets, 2)
+			}
+
+			match := [2]int{step.start, step.end}
+
+			if accepts(pos, prevEnd, match[:]) {
+				offsets = append(offsets, match[0], match[1])
+			}
+			pos, prevEnd = re.nextPos(b, pos, match[:]), match[1]
+		}
+	}
+
+	return chunk(offsets, 2)
+}
+
+// chunkBounds splits b into about n chunks starting at UTF-8 character
+// boundaries, and returns their offsets followed by len(b)+1, so that the last
+// chunk covers a match at the very end of b.
+func chunkBounds(b []byte, n int) []int {
+	bounds := []int{0}
+
+	for k := 1; k < n; k++ {
+		i := k * len(b) / n
+		for j := 0; j < utf8.UTFMax-1 && i < len(b) && !utf8.RuneStart(b[i]); j++ {
+			i++
+		}
+
+		if i > bounds[len(bounds)-1] && i < len(b) {
+			bounds = append(bounds, i)
+		}
+	}
+
+	return append(bounds, len(b)+1)
+}
+
+// trace searches b for successive matches as allMatches would, but starting
+// from start, and records every search made from below end. It stops early
+// once n matches were found, if n >= 0.
+func (re *PCREgexp) trace(m *matchData, b []byte, start, end, n int) []searchStep {
+	var steps []searchStep
+	var options uint32
+
+	for pos, found := start, 0; pos < end && pos <= len(b) && (n < 0 || found <= n); found++ {
+		if re.exec(m, b, pos, options) < 0 {
+			return append(steps, searchStep{pos: pos, start: -1, end: -1})
+		}
+		options = re.utfChecked()
+
+		steps = append(steps, searchStep{pos: pos, start: m.ovector[0], end: m.ovector[1]})
+		pos = re.nextPos(b, pos, m.ovector)
+	}
+
+	return steps
+}
+
+// parallel calls fn for each index below count, spread across up to workers
+// goroutines taking grain indexes at a time. Each goroutine passes its own
+// match data to fn. A panic in fn is raised again on the calling goroutine
+// once all goroutines are done.
+func (re *PCREgexp) parallel(workers, count, grain int, fn func(m *matchData, i int)) {
+	if re.code == 0 || count == 0 {
+		return
+	}
+
+	var next int64
+
+	work := func() {
+		m := re.getMatchData()
+		if m == nil {
+			return
+		}
+		defer re.putMatchData(m)
+
+		for {
+			start := int(atomic.AddInt64(&next, int64(grain)) - int64(grain))
+			if start >= count {
+				return
+			}
+
+			end := start + grain
+			if end > count {
+				end = count
+			}
+
+			for i := start; i < end; i++ {
+				fn(m, i)
+			}
+		}
+	}
+
+	if chunks := (count + grain - 1) / grain; workers > chunks {
+		workers = chunks
+	}
+
+	if workers <= 1 {
+		work()
+		return
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var panicked any
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() { panicked = r })
+				}
+			}()
+
+			work()
+		}()
+	}
+	wg.Wait()
+
+	if panicked != nil {
+		panic(panicked)
+	}
+}
@@ -0,0 +1,68 @@
+package pcregexp_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+// parallelBuffer returns a buffer large enough to be split into many chunks,
+// holding matches of very different lengths.
+func parallelBuffer() []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < 1<<20; i++ {
+		fmt.Fprintf(&b, "line %d: foo=bar <tag %s> héllo wörld\n", i, strings.Repeat("x", i%5000))
+	}
+
+	return b.Bytes()
+}
+
+func TestFindAllIndexParallel(t *testing.T) {
+	b := parallelBuffer()
+
+	tests := []struct {
+		pattern string
+		opts    pcregexp.Option
+		n       int
+	}{
+		{`\d+`, 0, -1},
+		{`<[^>]*>`, 0, -1},        // matches spanning chunk edges
+		{`(?<=foo=)bar`, 0, -1},   // lookbehind
+		{`\d+(?=:)`, 0, -1},       // lookahead
+		{`x*`, 0, -1},             // empty matches
+		{`(?s)tag.*?\n`, 0, 100},  // limited
+		{`ö|é`, pcregexp.UTF, -1}, // chunks start at character boundaries
+		{`\Gline`, 0, -1},         // anchored at the search offset
+		{`\z`, 0, -1},             // match at the very end
+		{`nope`, 0, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re := pcregexp.MustCompileWithOptions(tt.pattern, tt.opts)
+			defer re.Close()
+
+			want := re.FindAllIndex(b, tt.n)
+
+			for _, workers := range []int{2, 7} {
+				if got := re.FindAllIndexParallel(b, tt.n, workers); !reflect.DeepEqual(got, want) {
+					t.Errorf("FindAllIndexParallel(%d workers) returned %d matches, FindAllIndex %d", workers, len(got), len(want))
+				}
+			}
+		})
+	}
+}
+
+func TestFindAllIndexParallel_Small(t *testing.T) {
+	re := pcregexp.MustCompile(`\d+`)
+	defer re.Close()
+
+	b := []byte("a1 b22 c333")
+	if got, want := re.FindAllIndexParallel(b, -1, 4), re.FindAllIndex(b, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllIndexParallel() = %v, want %v", got, want)
+	}
+}
@@ -4,27 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"reflect"
 	"runtime"
-	"strings"
-	"unicode/utf8"
+	"sync"
 	"unsafe"
 
 	"github.com/ebitengine/purego"
 )
 
 func init() {
-	var libPath string
-
-	switch runtime.GOOS {
-	case "darwin":
-		libPath = "libpcre2-8.dylib"
-	case "linux", "freebsd":
-		libPath = "libpcre2-8.so"
-	case "windows":
-		libPath = "pcre2-8.dll"
-	default:
-		panic(fmt.Errorf("GOOS=%s is not supported", runtime.GOOS))
+	libPath, err := libraryName(8)
+	if err != nil {
+		panic(err)
 	}
 
 	lib, err := openLibrary(libPath)
@@ -38,26 +28,76 @@ func init() {
 		{&pcre2_compile, "pcre2_compile_8"},
 		{&pcre2_code_free, "pcre2_code_free_8"},
 		{&pcre2_pattern_info, "pcre2_pattern_info_8"},
-		{&pcre2_match, "pcre2_match_8"},
+		{&pcre2_dfa_match, "pcre2_dfa_match_8"},
+		{&pcre2_match_data_create, "pcre2_match_data_create_8"},
 		{&pcre2_match_data_create_from_pattern, "pcre2_match_data_create_from_pattern_8"},
 		{&pcre2_match_data_free, "pcre2_match_data_free_8"},
 		{&pcre2_get_ovector_pointer, "pcre2_get_ovector_pointer_8"},
+		{&pcre2_get_ovector_count, "pcre2_get_ovector_count_8"},
+		{&pcre2_get_mark, "pcre2_get_mark_8"},
+		{&pcre2_get_startchar, "pcre2_get_startchar_8"},
+		{&pcre2_match_context_create, "pcre2_match_context_create_8"},
+		{&pcre2_match_context_free, "pcre2_match_context_free_8"},
+		{&pcre2_set_callout, "pcre2_set_callout_8"},
+		{&pcre2_set_offset_limit, "pcre2_set_offset_limit_8"},
+		{&pcre2_callout_enumerate, "pcre2_callout_enumerate_8"},
+		{&pcre2_config, "pcre2_config_8"},
+		{&pcre2_serialize_encode, "pcre2_serialize_encode_8"},
+		{&pcre2_serialize_decode, "pcre2_serialize_decode_8"},
+		{&pcre2_serialize_free, "pcre2_serialize_free_8"},
+		{&pcre2_general_context_create, "pcre2_general_context_create_8"},
+		{&pcre2_general_context_free, "pcre2_general_context_free_8"},
+		{&pcre2_compile_context_create, "pcre2_compile_context_create_8"},
+		{&pcre2_compile_context_free, "pcre2_compile_context_free_8"},
 	}
 
 	for _, f := range funcs {
 		purego.RegisterLibFunc(f[0], lib, f[1].(string))
 	}
+
+	pcre2_match, err = lookupSymbol(lib, "pcre2_match_8")
+	if err != nil {
+		panic(fmt.Errorf("failed to load pcre2_match_8: %w", err))
+	}
+}
+
+// libraryName returns the file name of the PCRE2 library for code units of
+// the given width in bits.
+func libraryName(width int) (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return fmt.Sprintf("libpcre2-%d.dylib", width), nil
+	case "linux", "freebsd":
+		return fmt.Sprintf("libpcre2-%d.so", width), nil
+	case "windows":
+		return fmt.Sprintf("pcre2-%d.dll", width), nil
+	default:
+		return "", fmt.Errorf("GOOS=%s is not supported", runtime.GOOS)
+	}
 }
 
 type PCREgexp struct {
-	pattern   string  // original pattern
-	buf       []int   // cached match offsets
-	code      uintptr // pointer to compiled pcre2_code
-	matchData uintptr // cached match data
+	pattern   string       // original pattern
+	opts      Option       // compile options
+	code      uintptr      // pointer to compiled pcre2_code
+	pre       *prefilter   // Go-side check ruling out subjects, if any
+	shared    *cacheEntry  // owner of code, for handles returned by Cached
+	mem       *memAccount  // native memory allocated for code
+	mu        sync.Mutex   // guards matchData and calloutFn
+	matchData []*matchData // idle match data, reused across matches
+	calloutFn CalloutFunc  // callout function, if any
+	history   *PCREgexp    // auto-callout copy used by FindAllCaptures
+	historyMu sync.Mutex   // serializes FindAllCaptures
 }
 
 // Compile compiles the given pattern and returns a [PCREgexp].
 func Compile(pattern string) (*PCREgexp, error) {
+	return CompileWithOptions(pattern, 0)
+}
+
+// CompileWithOptions is like [Compile] but compiles the pattern with the
+// given PCRE2 compile options.
+func CompileWithOptions(pattern string, opts Option) (*PCREgexp, error) {
 	var patPtr *uint8
 	var errcode int32
 	var errOffset uint64
@@ -66,17 +106,37 @@ func Compile(pattern string) (*PCREgexp, error) {
 		var dummy byte = 0
 		patPtr = &dummy
 	} else {
-		strHeader := (*reflect.StringHeader)(unsafe.Pointer(&pattern))
-		patPtr = (*uint8)(unsafe.Pointer(strHeader.Data))
-		// patPtr = (*uint8)(unsafe.StringData(pattern))
+		patPtr = &stringToBytesUnsafe(pattern)[0]
+	}
+
+	mem, err := newMemAccount(1)
+	if err != nil {
+		return nil, err
+	}
+
+	ccontext := pcre2_compile_context_create(mem.gctx)
+	if ccontext == 0 {
+		mem.release()
+		return nil, memoryError("pcre2_compile_context_create")
 	}
 
-	code := pcre2_compile(patPtr, uint64(len(pattern)), 0, &errcode, &errOffset, 0)
+	code := pcre2_compile(patPtr, uint64(len(pattern)), uint32(opts), &errcode, &errOffset, ccontext)
+	pcre2_compile_context_free(ccontext)
+
 	if code == 0 {
+		mem.release()
+
+		if errcode == pcre2ErrorHeapFailed {
+			return nil, memoryError("pcre2_compile")
+		}
+
 		return nil, fmt.Errorf("pcre2_compile failed at offset %d, error code %d", errOffset, errcode)
 	}
 
-	return &PCREgexp{code: code, pattern: pattern}, nil
+	re := &PCREgexp{code: code, pattern: pattern, opts: opts, mem: mem}
+	re.pre = newPrefilter(re)
+
+	return re, nil
 }
 
 // MustCompile is like Compile but panics on error.
@@ -89,118 +149,123 @@ func MustCompile(pattern string) *PCREgexp {
 	return re
 }
 
-// Close frees the resources associated with the compiled pattern.
-func (re *PCREgexp) Close() {
-	if re.matchData != 0 {
-		pcre2_match_data_free(re.matchData)
-		re.matchData = 0
+// MustCompileWithOptions is like CompileWithOptions but panics on error.
+func MustCompileWithOptions(pattern string, opts Option) *PCREgexp {
+	re, err := CompileWithOptions(pattern, opts)
+	if err != nil {
+		panic(err)
 	}
 
-	if re.code != 0 {
-		pcre2_code_free(re.code)
-		re.code = 0
-	}
+	return re
 }
 
-// saveMatchData creates a new match data object if it doesn't exist yet.
+// Close frees the resources associated with the compiled pattern.
 //
-// It returns the pointer to the match data object. The match data object is
-// used to store the results of a match.
-func (re *PCREgexp) saveMatchData() uintptr {
-	if re.matchData == 0 {
-		re.matchData = pcre2_match_data_create_from_pattern(re.code, 0)
+// For a regexp returned by [Cached], the compiled code is only freed once the
+// pattern has left the cache and every other handle to it is closed.
+func (re *PCREgexp) Close() {
+	re.mu.Lock()
+	for _, m := range re.matchData {
+		m.free()
 	}
+	re.matchData = nil
+	re.calloutFn = nil
+	re.mu.Unlock()
 
-	return re.matchData
-}
+	// A running FindAllCaptures holds historyMu until it is done with the
+	// history copy, and sees the regexp closed once it gets it.
+	re.historyMu.Lock()
+	defer re.historyMu.Unlock()
 
-// match performs a PCRE2 match on the given subject.
-//
-// It returns a slice of start/end indexes as returned by PCRE2.
-func (re *PCREgexp) match(subject []byte) []int {
-	if re.code == 0 || len(subject) == 0 {
-		return nil
-	}
-
-	md := re.saveMatchData()
-	if md == 0 {
-		return nil
+	if re.history != nil {
+		re.history.Close()
+		re.history = nil
 	}
 
-	var subjectPtr *uint8
-
-	if len(subject) > 0 {
-		subjectPtr = (*uint8)(ptr(&subject[0]))
+	if re.code != 0 {
+		if re.shared != nil {
+			re.shared.release()
+		} else {
+			pcre2_code_free(re.code)
+			re.mem.release()
+		}
+		re.code = 0
+		re.mem = nil
 	}
+}
 
-	ret := pcre2_match(re.code, subjectPtr, uint64(len(subject)), 0, 0, md, 0)
-	if ret < 0 {
-		return nil
-	}
+// getMatchData returns an idle match data block, creating one if all are in
+// use, so that the regexp can be used by several goroutines at once. The block
+// calls the current callout function of the regexp, if any.
+//
+// The match data object is used to store the results of a match.
+func (re *PCREgexp) getMatchData() *matchData {
+	re.mu.Lock()
+	fn := re.calloutFn
+	if n := len(re.matchData); n > 0 {
+		m := re.matchData[n-1]
+		re.matchData = re.matchData[:n-1]
+		re.mu.Unlock()
 
-	n := int(ret)
-	reqLen := n * 2
+		m.context.callout.fn = fn
 
-	if cap(re.buf) < reqLen {
-		re.buf = make([]int, reqLen)
-	} else {
-		re.buf = re.buf[:reqLen]
+		return m
 	}
+	re.mu.Unlock()
 
-	ovector := pcre2_get_ovector_pointer(md)
-	if ovector == nil {
-		return nil
+	m := re.newMatchData()
+	if m != nil {
+		m.context.callout.fn = fn
 	}
 
-	size := unsafe.Sizeof(uint64(0))
-	for i := 0; i < reqLen; i++ {
-		ptr := (*uint64)(ptr(uintptr(ptr(ovector)) + uintptr(i)*size))
-		re.buf[i] = int(*ptr)
-	}
+	return m
+}
 
-	return re.buf
+// putMatchData hands m back for reuse by getMatchData.
+func (re *PCREgexp) putMatchData(m *matchData) {
+	re.mu.Lock()
+	re.matchData = append(re.matchData, m)
+	re.mu.Unlock()
 }
 
 // MatchString reports whether the Regexp matches the given string.
 func (re *PCREgexp) MatchString(s string) bool {
-	return re.match(stringToBytesUnsafe(s)) != nil
+	return re.Match(stringToBytesUnsafe(s))
 }
 
 // FindString returns the text of the leftmost match in s.
 func (re *PCREgexp) FindString(s string) string {
-	indexes := re.match(stringToBytesUnsafe(s))
-	if indexes == nil || len(indexes) < 2 {
+	var buf [2]int
+
+	a, ok := re.doMatch(buf[:0], stringToBytesUnsafe(s), 1)
+	if !ok {
 		return ""
 	}
 
-	return s[indexes[0]:indexes[1]]
+	return s[a[0]:a[1]]
 }
 
 // FindStringIndex returns a two-element slice of integers defining the start
 // and end of the leftmost match in s.
 func (re *PCREgexp) FindStringIndex(s string) []int {
-	return re.match(stringToBytesUnsafe(s))
+	return re.FindIndex(stringToBytesUnsafe(s))
 }
 
 // FindStringSubmatch returns a slice holding the text of the leftmost match and
-// its submatches. It uses the actual number of captured groups as returned by
-// PCRE2.
+// its submatches. Subexpressions that did not participate in the match yield
+// empty strings.
 func (re *PCREgexp) FindStringSubmatch(s string) []string {
-	indexes := re.match(stringToBytesUnsafe(s))
-	if indexes == nil || len(indexes) < 2 {
+	var buf [32]int
+
+	a, ok := re.doMatch(buf[:0], stringToBytesUnsafe(s), -1)
+	if !ok {
 		return nil
 	}
 
-	n := len(indexes) / 2
-	submatches := make([]string, n)
-
-	for i := 0; i < n; i++ {
-		start := indexes[2*i]
-		end := indexes[2*i+1]
-		if start < 0 || end < 0 {
-			submatches[i] = ""
-		} else {
-			submatches[i] = s[start:end]
+	submatches := make([]string, len(a)/2)
+	for i := range submatches {
+		if a[2*i] >= 0 {
+			submatches[i] = s[a[2*i]:a[2*i+1]]
 		}
 	}
 
@@ -210,96 +275,84 @@ func (re *PCREgexp) FindStringSubmatch(s string) []string {
 // ReplaceAllString returns a copy of src in which all matches of the [PCREgexp]
 // have been replaced by repl.
 //
-// If an empty match is encountered, it advances one UTF-8 rune to avoid
+// If an empty match is encountered, it advances one character to avoid
 // infinite loop.
 func (re *PCREgexp) ReplaceAllString(src, repl string) string {
-	if src == "" {
-		return ""
-	}
-
-	var b strings.Builder
-	b.Grow(len(src))
+	b := re.replaceAll(make([]byte, 0, len(src)), stringToBytesUnsafe(src), func(dst []byte, _ []int) []byte {
+		return append(dst, repl...)
+	})
 
-	remaining := src
-	for {
-		indexes := re.match(stringToBytesUnsafe(remaining))
-		if indexes == nil || len(indexes) < 2 || indexes[0] < 0 {
-			b.WriteString(remaining)
-			break
-		}
-
-		b.WriteString(remaining[:indexes[0]])
-		b.WriteString(repl)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] < len(remaining) {
-				r, size := utf8.DecodeRuneInString(remaining[indexes[1]:])
-				if r == utf8.RuneError || size == 0 {
-					b.WriteString(remaining[indexes[1]:])
-					break
-				}
-
-				b.WriteString(remaining[indexes[1] : indexes[1]+size])
-				remaining = remaining[indexes[1]+size:]
-			} else {
-				remaining = ""
-			}
-		} else {
-			remaining = remaining[indexes[1]:]
-		}
-	}
-
-	return b.String()
+	return bytesToStringUnsafe(b)
 }
 
 // Find returns a slice holding the text of the leftmost match in b.
 func (re *PCREgexp) Find(b []byte) []byte {
-	indexes := re.match(b)
-	if indexes == nil || len(indexes) < 2 {
+	var buf [2]int
+
+	a, ok := re.doMatch(buf[:0], b, 1)
+	if !ok {
 		return nil
 	}
-	result := make([]byte, indexes[1]-indexes[0])
-	copy(result, b[indexes[0]:indexes[1]])
-	return result
+
+	return b[a[0]:a[1]:a[1]]
 }
 
 // Match reports whether the regexp matches the byte slice b.
+//
+// Match does not allocate on the Go side, except on platforms other than
+// Linux, macOS and FreeBSD on amd64 or arm64, where calling PCRE2 costs one
+// allocation.
 func (re *PCREgexp) Match(b []byte) bool {
-	return re.match(b) != nil
+	_, ok := re.doMatch(nil, b, 0)
+	return ok
 }
 
 // FindIndex returns a two-element slice of integers defining the location of
 // the leftmost match in b.
 func (re *PCREgexp) FindIndex(b []byte) []int {
-	return re.match(b)
+	a, ok := re.doMatch(nil, b, 1)
+	if !ok {
+		return nil
+	}
+
+	return a
 }
 
 // FindSubmatch returns a slice of slices holding the text of the leftmost
 // match and the matches of any subexpressions.
 func (re *PCREgexp) FindSubmatch(b []byte) [][]byte {
-	indexes := re.match(b)
-	if indexes == nil || len(indexes) < 2 {
+	var buf [32]int
+
+	a, ok := re.doMatch(buf[:0], b, -1)
+	if !ok {
 		return nil
 	}
 
-	matches := make([][]byte, len(indexes)/2)
-	for i := 0; i < len(matches); i++ {
-		start := indexes[2*i]
-		end := indexes[2*i+1]
-		if start < 0 || end < 0 {
-			matches[i] = nil
-		} else {
-			matches[i] = make([]byte, end-start)
-			copy(matches[i], b[start:end])
+	return submatches(b, a)
+}
+
+// submatches returns the slices of b at the index pairs of a match, or nil for
+// subexpressions that did not participate in it.
+func submatches(b []byte, a []int) [][]byte {
+	matches := make([][]byte, len(a)/2)
+	for i := range matches {
+		if a[2*i] >= 0 {
+			matches[i] = b[a[2*i]:a[2*i+1]:a[2*i+1]]
 		}
 	}
+
 	return matches
 }
 
 // FindSubmatchIndex returns a slice holding the index pairs identifying the
 // leftmost match and the matches of any subexpressions.
 func (re *PCREgexp) FindSubmatchIndex(b []byte) []int {
-	return re.match(b)
+	a, ok := re.doMatch(nil, b, -1)
+	if !ok {
+		return nil
+	}
+
+	return a
 }
 
 // FindReaderIndex returns a two-element slice of integers defining the location
@@ -364,15 +417,23 @@ func (re *PCREgexp) MatchReader(r io.RuneReader) bool {
 
 // ReplaceAll returns a copy of src, replacing matches of the regexp with repl.
 func (re *PCREgexp) ReplaceAll(src, repl []byte) []byte {
-	return stringToBytesUnsafe(re.ReplaceAllString(string(src), string(repl)))
+	return re.replaceAll(make([]byte, 0, len(src)), src, func(dst []byte, _ []int) []byte {
+		return append(dst, repl...)
+	})
 }
 
 // NumSubexp returns the number of parenthesized subexpressions in this regexp.
-//
-// TODO(dwisiswant0): Implement this method.
 func (re *PCREgexp) NumSubexp() int {
-	// TODO(dwisiswant0): Implement this method.
-	return 0
+	if re.code == 0 {
+		return 0
+	}
+
+	var n uint32
+	if pcre2_pattern_info(re.code, pcre2InfoCaptureCount, ptr(&n)) != 0 {
+		return 0
+	}
+
+	return int(n)
 }
 
 // String returns the source text used to compile the regexp.
@@ -380,37 +441,20 @@ func (re *PCREgexp) String() string {
 	return re.pattern
 }
 
+// Options returns the compile options the regexp was compiled with.
+func (re *PCREgexp) Options() Option {
+	return re.opts
+}
+
 // FindAllString returns a slice of all successive matches of the regexp in s.
 // If n < 0, the return value contains all matches. If n >= 0, the return value
 // contains at most n matches.
 func (re *PCREgexp) FindAllString(s string, n int) []string {
-	if n == 0 {
-		return nil
-	}
-
 	var matches []string
-	remaining := s
-
-	for n != 0 {
-		indexes := re.match(stringToBytesUnsafe(remaining))
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		matches = append(matches, remaining[indexes[0]:indexes[1]])
 
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRuneInString(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-		} else {
-			remaining = remaining[indexes[1]:]
-		}
-
-		n--
-	}
+	re.allMatches(stringToBytesUnsafe(s), n, func(match []int) {
+		matches = append(matches, s[match[0]:match[1]])
+	})
 
 	return matches
 }
@@ -418,32 +462,17 @@ func (re *PCREgexp) FindAllString(s string, n int) []string {
 // FindAllStringSubmatch is like [FindStringSubmatch] but returns successive
 // matches.
 func (re *PCREgexp) FindAllStringSubmatch(s string, n int) [][]string {
-	if n == 0 {
-		return nil
-	}
-
 	var results [][]string
-	remaining := s
-
-	for n != 0 {
-		match := re.FindStringSubmatch(remaining)
-		if match == nil {
-			break
-		}
-		results = append(results, match)
 
-		if len(match[0]) == 0 {
-			if len(remaining) == 0 {
-				break
+	re.allMatches(stringToBytesUnsafe(s), n, func(match []int) {
+		submatches := make([]string, len(match)/2)
+		for i := range submatches {
+			if match[2*i] >= 0 {
+				submatches[i] = s[match[2*i]:match[2*i+1]]
 			}
-			_, size := utf8.DecodeRuneInString(remaining)
-			remaining = remaining[size:]
-		} else {
-			remaining = remaining[len(match[0]):]
 		}
-
-		n--
-	}
+		results = append(results, submatches)
+	})
 
 	return results
 }
@@ -451,140 +480,35 @@ func (re *PCREgexp) FindAllStringSubmatch(s string, n int) [][]string {
 // FindAllStringIndex returns a slice of index pairs identifying successive
 // matches of the regexp in s.
 func (re *PCREgexp) FindAllStringIndex(s string, n int) [][]int {
-	if n == 0 {
-		return nil
-	}
-
-	var results [][]int
-	remaining := s
-	offset := 0
-
-	for n != 0 {
-		indexes := re.match(stringToBytesUnsafe(remaining))
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		// Adjust indexes for the offset
-		adjIndexes := make([]int, 2)
-		adjIndexes[0] = indexes[0] + offset
-		adjIndexes[1] = indexes[1] + offset
-		results = append(results, adjIndexes)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRuneInString(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-			offset += indexes[1] + size
-		} else {
-			remaining = remaining[indexes[1]:]
-			offset += indexes[1]
-		}
-
-		n--
-	}
-
-	return results
+	return re.FindAllIndex(stringToBytesUnsafe(s), n)
 }
 
 // ReplaceAllFunc returns a copy of src in which all matches of the regexp
 // have been replaced by the return value of function repl applied to the
 // matched byte slice.
 func (re *PCREgexp) ReplaceAllFunc(src []byte, repl func([]byte) []byte) []byte {
-	var b strings.Builder
-	remaining := src
-	lastMatchEnd := 0
-
-	for {
-		indexes := re.match(remaining)
-		if indexes == nil || len(indexes) < 2 {
-			b.Write(remaining[lastMatchEnd:])
-			break
-		}
-
-		b.Write(remaining[:indexes[0]])
-		match := remaining[indexes[0]:indexes[1]]
-		b.Write(repl(match))
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			r, size := utf8.DecodeRune(remaining[indexes[1]:])
-			if r == utf8.RuneError {
-				b.Write(remaining[indexes[1]:])
-				break
-			}
-			b.Write(remaining[indexes[1] : indexes[1]+size])
-			remaining = remaining[indexes[1]+size:]
-		} else {
-			remaining = remaining[indexes[1]:]
-		}
-	}
-
-	return stringToBytesUnsafe(b.String())
+	return re.replaceAll(make([]byte, 0, len(src)), src, func(dst []byte, match []int) []byte {
+		return append(dst, repl(src[match[0]:match[1]:match[1]])...)
+	})
 }
 
 // Split slices s into substrings separated by matches of the regexp.
 // If n > 0, Split returns at most n substrings, otherwise it returns all
 // substrings.
 func (re *PCREgexp) Split(s string, n int) []string {
-	if n == 0 {
-		return nil
-	}
-
-	var parts []string
-	pos := 0
-	for {
-		indexes := re.FindStringIndex(s[pos:])
-		if indexes == nil || indexes[0] < 0 {
-			parts = append(parts, s[pos:])
-			break
-		}
-
-		parts = append(parts, s[pos:pos+indexes[0]])
-		pos += indexes[1]
-
-		if n > 0 && len(parts) == n-1 {
-			parts = append(parts, s[pos:])
-			break
-		}
-	}
-	return parts
+	return splitStrings(s, n, re.split(stringToBytesUnsafe(s), n, splitDrop))
 }
 
 // FindAll returns a slice of all successive matches of the regexp in b.
 func (re *PCREgexp) FindAll(b []byte, n int) [][]byte {
-	if n == 0 {
+	a := re.AppendFindAllIndex(nil, b, n)
+	if a == nil {
 		return nil
 	}
 
-	var matches [][]byte
-	remaining := b
-
-	for n != 0 {
-		indexes := re.match(remaining)
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		match := make([]byte, indexes[1]-indexes[0])
-		copy(match, remaining[indexes[0]:indexes[1]])
-		matches = append(matches, match)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRune(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-		} else {
-			remaining = remaining[indexes[1]:]
-		}
-
-		n--
+	matches := make([][]byte, len(a)/2)
+	for i := range matches {
+		matches[i] = b[a[2*i]:a[2*i+1]:a[2*i+1]]
 	}
 
 	return matches
@@ -593,41 +517,7 @@ func (re *PCREgexp) FindAll(b []byte, n int) [][]byte {
 // FindAllIndex returns a slice of index pairs identifying successive matches of
 // the regexp in b.
 func (re *PCREgexp) FindAllIndex(b []byte, n int) [][]int {
-	if n == 0 {
-		return nil
-	}
-
-	var results [][]int
-	remaining := b
-	offset := 0
-
-	for n != 0 {
-		indexes := re.match(remaining)
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		adjIndexes := make([]int, 2)
-		adjIndexes[0] = indexes[0] + offset
-		adjIndexes[1] = indexes[1] + offset
-		results = append(results, adjIndexes)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRune(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-			offset += indexes[1] + size
-		} else {
-			remaining = remaining[indexes[1]:]
-			offset += indexes[1]
-		}
-
-		n--
-	}
-
-	return results
+	return chunk(re.AppendFindAllIndex(nil, b, n), 2)
 }
 
 // ReplaceAllLiteral returns a copy of src, replacing matches of the regexp with
@@ -646,121 +536,33 @@ func (re *PCREgexp) ReplaceAllLiteralString(src, repl string) string {
 // have been replaced by the return value of function repl applied to the
 // matched text.
 func (re *PCREgexp) ReplaceAllStringFunc(src string, repl func(string) string) string {
-	var b strings.Builder
-	remaining := src
-	lastMatchEnd := 0
-
-	for {
-		indexes := re.match(stringToBytesUnsafe(remaining))
-		if indexes == nil || len(indexes) < 2 {
-			b.WriteString(remaining[lastMatchEnd:])
-			break
-		}
+	b := re.replaceAll(make([]byte, 0, len(src)), stringToBytesUnsafe(src), func(dst []byte, match []int) []byte {
+		return append(dst, repl(src[match[0]:match[1]])...)
+	})
 
-		b.WriteString(remaining[:indexes[0]])
-		match := remaining[indexes[0]:indexes[1]]
-		b.WriteString(repl(match))
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			r, size := utf8.DecodeRuneInString(remaining[indexes[1]:])
-			if r == utf8.RuneError {
-				b.WriteString(remaining[indexes[1]:])
-				break
-			}
-			b.WriteString(remaining[indexes[1] : indexes[1]+size])
-			remaining = remaining[indexes[1]+size:]
-		} else {
-			remaining = remaining[indexes[1]:]
-		}
-	}
-
-	return b.String()
+	return bytesToStringUnsafe(b)
 }
 
 // FindStringSubmatchIndex returns a slice holding the index pairs identifying
 // the leftmost match of the regexp in s and the matches of its subexpressions.
 func (re *PCREgexp) FindStringSubmatchIndex(s string) []int {
-	return re.match(stringToBytesUnsafe(s))
+	return re.FindSubmatchIndex(stringToBytesUnsafe(s))
 }
 
 // FindAllStringSubmatchIndex returns a slice of slices holding the index pairs
 // identifying the successive matches of the regexp in s and their
 // subexpressions.
 func (re *PCREgexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
-	if n == 0 {
-		return nil
-	}
-
-	var results [][]int
-	remaining := s
-	offset := 0
-
-	for n != 0 {
-		indexes := re.match(stringToBytesUnsafe(remaining))
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		// Adjust indexes for the offset
-		adjIndexes := make([]int, len(indexes))
-		for i := 0; i < len(indexes); i++ {
-			if indexes[i] >= 0 {
-				adjIndexes[i] = indexes[i] + offset
-			} else {
-				adjIndexes[i] = indexes[i]
-			}
-		}
-		results = append(results, adjIndexes)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRuneInString(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-			offset += indexes[1] + size
-		} else {
-			remaining = remaining[indexes[1]:]
-			offset += indexes[1]
-		}
-
-		n--
-	}
-
-	return results
+	return re.FindAllSubmatchIndex(stringToBytesUnsafe(s), n)
 }
 
 // FindAllSubmatch returns a slice of successive matches of the regexp in b.
 func (re *PCREgexp) FindAllSubmatch(b []byte, n int) [][][]byte {
-	if n == 0 {
-		return nil
-	}
-
 	var results [][][]byte
-	remaining := b
 
-	for n != 0 {
-		match := re.FindSubmatch(remaining)
-		if match == nil {
-			break
-		}
-		results = append(results, match)
-
-		if len(match[0]) == 0 {
-			if len(remaining) == 0 {
-				break
-			}
-			_, size := utf8.DecodeRune(remaining)
-			remaining = remaining[size:]
-		} else {
-			remaining = remaining[len(match[0]):]
-		}
-
-		n--
-	}
+	re.allMatches(b, n, func(match []int) {
+		results = append(results, submatches(b, match))
+	})
 
 	return results
 }
@@ -768,47 +570,7 @@ func (re *PCREgexp) FindAllSubmatch(b []byte, n int) [][][]byte {
 // FindAllSubmatchIndex returns a slice of successive matches indexes of the
 // regexp in b.
 func (re *PCREgexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
-	if n == 0 {
-		return nil
-	}
-
-	var results [][]int
-	remaining := b
-	offset := 0
-
-	for n != 0 {
-		indexes := re.match(remaining)
-		if indexes == nil || len(indexes) < 2 {
-			break
-		}
-
-		// Adjust indexes for the offset
-		adjIndexes := make([]int, len(indexes))
-		for i := 0; i < len(indexes); i++ {
-			if indexes[i] >= 0 {
-				adjIndexes[i] = indexes[i] + offset
-			} else {
-				adjIndexes[i] = indexes[i]
-			}
-		}
-		results = append(results, adjIndexes)
-
-		if indexes[0] == indexes[1] {
-			if indexes[1] >= len(remaining) {
-				break
-			}
-			_, size := utf8.DecodeRune(remaining[indexes[1]:])
-			remaining = remaining[indexes[1]+size:]
-			offset += indexes[1] + size
-		} else {
-			remaining = remaining[indexes[1]:]
-			offset += indexes[1]
-		}
-
-		n--
-	}
-
-	return results
+	return chunk(re.appendAll(nil, b, n, -1))
 }
 
 // Expand appends template to dst and returns the result; during the
@@ -877,33 +639,88 @@ func (re *PCREgexp) MarshalText() ([]byte, error) {
 	return stringToBytesUnsafe(re.String()), nil
 }
 
-// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The
+// regexp is compiled anew from text, and whatever it held before, including
+// its callout function, is released; it must not be in use meanwhile.
+//
+// Handles returned by [Cached] share their compiled code and cannot be
+// unmarshaled into.
 func (re *PCREgexp) UnmarshalText(text []byte) error {
+	if re.shared != nil {
+		return fmt.Errorf("UnmarshalText called on a cached regexp")
+	}
+
 	r, err := Compile(string(text))
 	if err != nil {
 		return err
 	}
-	*re = *r
+
+	re.Close()
+
+	re.mu.Lock()
+	re.pattern, re.opts, re.code, re.mem, re.pre = r.pattern, r.opts, r.code, r.mem, r.pre
+	re.matchData, r.matchData = r.matchData, nil
+	re.mu.Unlock()
+
 	return nil
 }
 
 // SubexpNames returns the names of the parenthesized subexpressions
 // in this regexp. The name for the first sub-expression is at index 1,
-// following the same convention as index in FindSubmatch.
-//
-// TODO(dwisiswant0): Implement this method.
+// following the same convention as index in FindSubmatch. Unnamed
+// subexpressions have empty names.
 func (re *PCREgexp) SubexpNames() []string {
-	// TODO(dwisiswant0): Implement using PCRE2_INFO_NAMECOUNT and
-	// PCRE2_INFO_NAMETABLE
-	return nil
+	if re.code == 0 {
+		return nil
+	}
+
+	names := make([]string, re.NumSubexp()+1)
+	re.nameTable(func(group int, name string) {
+		if group < len(names) && names[group] == "" {
+			names[group] = name
+		}
+	})
+
+	return names
 }
 
 // SubexpIndex returns the index of the first subexpression with the given name,
 // or -1 if there is no subexpression with that name.
-//
-// TODO(dwisiswant0): Implement this method.
 func (re *PCREgexp) SubexpIndex(name string) int {
-	// TODO(dwisiswant0): Implement using PCRE2_INFO_NAMECOUNT and
-	// PCRE2_INFO_NAMETABLE
-	return -1
+	index := -1
+	re.nameTable(func(group int, n string) {
+		if n == name && (index < 0 || group < index) {
+			index = group
+		}
+	})
+
+	return index
+}
+
+// nameTable calls fn for each entry of the name table of the compiled
+// pattern, which PCRE2 sorts by name.
+func (re *PCREgexp) nameTable(fn func(group int, name string)) {
+	if re.code == 0 {
+		return
+	}
+
+	count, size := re.info(pcre2InfoNameCount), re.info(pcre2InfoNameEntrySize)
+
+	var table *byte
+	if count == 0 || pcre2_pattern_info(re.code, pcre2InfoNameTable, ptr(&table)) != 0 || table == nil {
+		return
+	}
+
+	// Each entry holds the group number, most significant byte first, and
+	// the name, padded with NULs.
+	entries := unsafe.Slice(table, count*size)
+	for i := uint32(0); i < count; i++ {
+		entry := entries[i*size : (i+1)*size]
+		name := entry[2:]
+		if j := bytes.IndexByte(name, 0); j >= 0 {
+			name = name[:j]
+		}
+
+		fn(int(entry[0])<<8|int(entry[1]), string(name))
+	}
 }
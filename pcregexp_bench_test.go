@@ -2,6 +2,7 @@ package pcregexp_test
 
 import (
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -41,6 +42,8 @@ func BenchmarkMatchString(b *testing.B) {
 	}{
 		{"simple", `p([a-z]+)ch`, "peach punch pinch"},
 		{"email", `\b\w+@\w+\.\w+\b`, "test@example.com"},
+		{"prefiltered", `\b\w+@example\.com\b`, "no address in here"},
+		{"literal", `example\.com`, "test@example.com"},
 		// {"backreference", `(\w+)\s+\1`, "hello hello world"},
 		// {"lookaround", `(?<=foo)bar`, "foobar"},
 	}
@@ -94,6 +97,15 @@ func BenchmarkFind(b *testing.B) {
 			}
 		})
 
+		b.Run("pcregexp/AppendFindIndex/"+tt.name, func(b *testing.B) {
+			data := []byte(tt.text)
+			dst := make([]int, 0, 2)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pcre.AppendFindIndex(dst[:0], data)
+			}
+		})
+
 		b.Run("pcregexp/FindString/"+tt.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
 				pcre.FindString(tt.text)
@@ -329,3 +341,116 @@ func BenchmarkRuneReader(b *testing.B) {
 		}
 	})
 }
+
+func TestAllocs(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" || runtime.GOOS == "windows" {
+		// Calls into PCRE2 go through purego.SyscallN, which allocates.
+		t.Skip("calls into PCRE2 allocate on", runtime.GOOS+"/"+runtime.GOARCH)
+	}
+
+	re := pcregexp.MustCompile(`p([a-z]+)ch`)
+	defer re.Close()
+
+	literal := pcregexp.MustCompile(`punch`)
+	defer literal.Close()
+
+	text := "peach punch pinch"
+	data := []byte(text)
+	none := []byte("abc def ghi")
+	repl := []byte("FRUIT")
+	dst := make([]int, 0, 16)
+
+	upper, err := re.CompileTemplate(`P${1}CH`)
+	if err != nil {
+		t.Fatalf("CompileTemplate() error = %v", err)
+	}
+	upper = upper.PreserveCase()
+
+	tests := []struct {
+		name string
+		want float64 // allocations per call
+		fn   func()
+	}{
+		{"Match", 0, func() { re.Match(data) }},
+		// Rejected by the prefilter, without calling PCRE2.
+		{"Match/no match", 0, func() { re.Match(none) }},
+		{"Match/literal", 0, func() { literal.Match(data) }},
+		{"MatchString", 0, func() { re.MatchString(text) }},
+		{"Find", 0, func() { re.Find(data) }},
+		{"FindString", 0, func() { re.FindString(text) }},
+		{"AppendFindIndex", 0, func() { re.AppendFindIndex(dst[:0], data) }},
+		{"AppendFindStringSubmatchIndex", 0, func() { re.AppendFindStringSubmatchIndex(dst[:0], text) }},
+		{"AppendFindAllIndex", 0, func() { re.AppendFindAllIndex(dst[:0], data, -1) }},
+		{"Count", 0, func() { re.Count(data) }},
+		{"CountString", 0, func() { re.CountString(text) }},
+		{"CountUpTo", 0, func() { re.CountUpTo(data, 2) }},
+		{"Count/literal", 0, func() { literal.Count(data) }},
+		// Offsets grow by appending to one slice, then are cut into pairs.
+		{"FindAllIndex", 4, func() { re.FindAllIndex(data, -1) }},
+		// The result is built in a single buffer sized after the input.
+		{"ReplaceAll", 1, func() { re.ReplaceAll(data, repl) }},
+		// Case is applied from a scratch buffer reused across matches.
+		{"ReplaceAllTemplate/PreserveCase", 2, func() { re.ReplaceAllTemplate(data, upper) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testing.AllocsPerRun(100, tt.fn); got != tt.want {
+				t.Errorf("%s allocates %v times per call, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMatchStrings(b *testing.B) {
+	lines := make([]string, 10000)
+	for i := range lines {
+		lines[i] = "2024-01-02 12:00:00 INFO request served in 12ms"
+		if i%10 == 0 {
+			lines[i] = "2024-01-02 12:00:00 ERROR request failed after 3 retries"
+		}
+	}
+
+	pcre := pcregexp.MustCompile(`ERROR .* (\d+) retries`)
+	defer pcre.Close()
+
+	b.Run("pcregexp/MatchString", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				pcre.MatchString(line)
+			}
+		}
+	})
+
+	b.Run("pcregexp/MatchStrings", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pcre.MatchStrings(lines)
+		}
+	})
+
+	b.Run("pcregexp/Batch", func(b *testing.B) {
+		batch := pcre.Batch(0)
+		for i := 0; i < b.N; i++ {
+			batch.MatchStrings(lines)
+		}
+	})
+}
+
+func BenchmarkFindAllIndexParallel(b *testing.B) {
+	data := []byte(strings.Repeat("2024-01-02 12:00:00 GET /index.html 200 user@example.com\n", 1<<15))
+
+	pcre := pcregexp.MustCompile(`\b\w+@\w+\.\w+\b`)
+	defer pcre.Close()
+
+	b.Run("pcregexp/FindAllIndex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pcre.FindAllIndex(data, -1)
+		}
+	})
+
+	b.Run("pcregexp/FindAllIndexParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pcre.FindAllIndexParallel(data, -1, 0)
+		}
+	})
+}
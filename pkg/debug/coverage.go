@@ -0,0 +1,225 @@
+package debug
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Coverage accumulates which items of a pattern were exercised while matching
+// a corpus of subjects.
+type Coverage struct {
+	// Pattern is the pattern being covered.
+	Pattern string
+
+	// Items holds every item of the pattern, ordered by pattern offset.
+	Items []ItemCoverage
+
+	// Subjects is the number of subjects added.
+	Subjects int
+
+	// Matched is the number of subjects the pattern matched.
+	Matched int
+}
+
+// ItemCoverage tells how often one item of a pattern was exercised.
+type ItemCoverage struct {
+	// PatternOffset is the offset of the item in the pattern.
+	PatternOffset int
+
+	// Item is the text of the item.
+	Item string
+
+	// Visits is the number of times PCRE2 was about to match the item.
+	Visits int
+
+	// Matches is the number of times the item is taken to have matched.
+	// PCRE2 does not report whether an item matched, so this is inferred
+	// from the trace: an item counts as matched when the next step carries
+	// on with the same match attempt without backtracking, or when it is the
+	// last step of a successful match.
+	Matches int
+}
+
+// Covered reports whether the item matched at least once, as inferred for
+// Matches.
+func (i ItemCoverage) Covered() bool {
+	return i.Matches > 0
+}
+
+// Coverage returns an empty coverage listing every item of the pattern.
+func (d *Debugger) Coverage() *Coverage {
+	pattern := d.re.String()
+	c := &Coverage{Pattern: pattern}
+
+	for _, info := range d.re.Callouts() {
+		if info.NextItemLength == 0 {
+			continue // end of the pattern
+		}
+
+		c.Items = append(c.Items, ItemCoverage{
+			PatternOffset: info.PatternOffset,
+			Item:          pattern[info.PatternOffset : info.PatternOffset+info.NextItemLength],
+		})
+	}
+
+	sort.Slice(c.Items, func(i, j int) bool {
+		return c.Items[i].PatternOffset < c.Items[j].PatternOffset
+	})
+
+	return c
+}
+
+// Cover traces every subject of corpus and returns the resulting coverage.
+// Subjects PCRE2 would normally reject without trying the pattern, such as
+// those lacking a character every match starts with, still visit the items
+// they fail at.
+func (d *Debugger) Cover(corpus []string) *Coverage {
+	c := d.Coverage()
+	for _, s := range corpus {
+		c.Add(d.TraceString(s))
+	}
+
+	return c
+}
+
+// Add aggregates the steps of t, a trace of the same pattern.
+func (c *Coverage) Add(t *Trace) {
+	c.Subjects++
+	if t.Match != nil {
+		c.Matched++
+	}
+
+	for k, s := range t.Steps {
+		item := c.item(s.PatternOffset)
+		if item == nil {
+			continue
+		}
+
+		item.Visits++
+
+		if k+1 == len(t.Steps) {
+			// The last step of a successful match completed it.
+			if t.Match != nil {
+				item.Matches++
+			}
+			continue
+		}
+
+		// Matching went on within the same attempt without backtracking,
+		// which is taken for the item having matched.
+		next := t.Steps[k+1]
+		if !next.Backtrack && !next.StartMatch && next.Start == s.Start {
+			item.Matches++
+		}
+	}
+}
+
+// item returns the item at the given pattern offset, or nil.
+func (c *Coverage) item(offset int) *ItemCoverage {
+	i := sort.Search(len(c.Items), func(i int) bool {
+		return c.Items[i].PatternOffset >= offset
+	})
+	if i < len(c.Items) && c.Items[i].PatternOffset == offset {
+		return &c.Items[i]
+	}
+
+	return nil
+}
+
+// Uncovered returns the items that never matched.
+func (c *Coverage) Uncovered() []ItemCoverage {
+	var items []ItemCoverage
+	for _, item := range c.Items {
+		if !item.Covered() {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Ratio returns the fraction of items that matched at least once.
+func (c *Coverage) Ratio() float64 {
+	if len(c.Items) == 0 {
+		return 1
+	}
+
+	return float64(len(c.Items)-len(c.Uncovered())) / float64(len(c.Items))
+}
+
+// String returns the annotated pattern as written by WriteText.
+func (c *Coverage) String() string {
+	var b strings.Builder
+	c.WriteText(&b)
+
+	return b.String()
+}
+
+// WriteText writes the pattern to w, followed by a line marking the items that
+// were visited but never matched with '~' and those never visited with '^',
+// and a one-line summary.
+func (c *Coverage) WriteText(w io.Writer) error {
+	marker := []byte(strings.Repeat(" ", len(c.Pattern)))
+	for _, item := range c.Items {
+		var m byte
+		switch {
+		case item.Visits == 0:
+			m = '^'
+		case item.Matches == 0:
+			m = '~'
+		default:
+			continue
+		}
+
+		for i := 0; i < len(item.Item); i++ {
+			marker[item.PatternOffset+i] = m
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s\n%s\n%d/%d items covered (%.1f%%), %d/%d subjects matched\n",
+		c.Pattern, strings.TrimRight(string(marker), " "),
+		len(c.Items)-len(c.Uncovered()), len(c.Items), 100*c.Ratio(),
+		c.Matched, c.Subjects)
+
+	return err
+}
+
+// WriteHTML writes the pattern to w as an HTML <pre> element in which every
+// item is wrapped in a <span> of class "covered", "partial" (visited but never
+// matched) or "uncovered" (never visited), titled with its counts.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString(`<pre class="pcregexp-coverage">`)
+
+	pos := 0
+	for _, item := range c.Items {
+		if item.PatternOffset < pos {
+			continue
+		}
+		b.WriteString(html.EscapeString(c.Pattern[pos:item.PatternOffset]))
+
+		class := "covered"
+		switch {
+		case item.Visits == 0:
+			class = "uncovered"
+		case item.Matches == 0:
+			class = "partial"
+		}
+
+		fmt.Fprintf(&b, `<span class="%s" title="visits: %d, matches: %d">%s</span>`,
+			class, item.Visits, item.Matches, html.EscapeString(item.Item))
+
+		pos = item.PatternOffset + len(item.Item)
+	}
+
+	b.WriteString(html.EscapeString(c.Pattern[pos:]))
+	b.WriteString("</pre>\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
@@ -0,0 +1,95 @@
+package debug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestCover(t *testing.T) {
+	d := MustCompile(`^(?:cat|dog)s?$|bird`, 0)
+	defer d.Close()
+
+	c := d.Cover([]string{"cat", "cats", "fish"})
+
+	if c.Subjects != 3 || c.Matched != 2 {
+		t.Errorf("Subjects, Matched = %d, %d, want 3, 2", c.Subjects, c.Matched)
+	}
+
+	var uncovered []string
+	for _, item := range c.Uncovered() {
+		uncovered = append(uncovered, item.Item)
+	}
+	if got, want := strings.Join(uncovered, " "), "d o g ) b i r d"; got != want {
+		t.Errorf("Uncovered() = %q, want %q", got, want)
+	}
+
+	want := strings.Join([]string{
+		`^(?:cat|dog)s?$|bird`,
+		`        ~^^^    ~^^^`,
+		"",
+	}, "\n")
+	if got := c.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("String() =\n%s\nwant prefix\n%s", got, want)
+	}
+}
+
+func TestCoverage_WriteHTML(t *testing.T) {
+	d := MustCompile(`a<b|c`, 0)
+	defer d.Close()
+
+	c := d.Cover([]string{"a<b"})
+
+	var b strings.Builder
+	if err := c.WriteHTML(&b); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+
+	got := b.String()
+	for _, want := range []string{
+		`<span class="covered" title="visits: 1, matches: 1">&lt;</span>`,
+		`<span class="uncovered" title="visits: 0, matches: 0">c</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteHTML() = %s, want it to contain %s", got, want)
+		}
+	}
+}
+
+func TestCover_StartOptimized(t *testing.T) {
+	const pattern = `cat|dog`
+
+	// PCRE2 rejects "fish" from its first code units alone, without a single
+	// callout, unless the start optimizations are disabled.
+	re := pcregexp.MustCompileWithOptions(pattern, pcregexp.AutoCallout)
+	defer re.Close()
+
+	calls := 0
+	re.SetCallout(func(c *pcregexp.Callout) int {
+		calls++
+		return pcregexp.CalloutContinue
+	})
+	if re.MatchString("fish") || calls != 0 {
+		t.Fatalf("optimized match of %q made %d callouts, want 0", "fish", calls)
+	}
+
+	d := MustCompile(pattern, 0)
+	defer d.Close()
+
+	c := d.Cover([]string{"fish"})
+
+	for _, item := range c.Items {
+		switch item.Item {
+		case "c", "d":
+			// One failed attempt at every offset, the end included.
+			if item.Visits != 5 || item.Matches != 0 {
+				t.Errorf("item %q: Visits, Matches = %d, %d, want 5, 0", item.Item, item.Visits, item.Matches)
+			}
+		default:
+			if item.Visits != 0 {
+				t.Errorf("item %q: Visits = %d, want 0", item.Item, item.Visits)
+			}
+		}
+	}
+}
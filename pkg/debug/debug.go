@@ -0,0 +1,339 @@
+// Package debug traces how PCRE2 matches a pattern against a subject.
+//
+// Patterns are compiled with [pcregexp.AutoCallout], so that PCRE2 calls back
+// before every item of the pattern, and with [pcregexp.NoAutoPossess] and
+// [pcregexp.NoStartOptimize], so that no match attempt or backtrack is
+// optimized away. Each call is recorded as a [Step] of a [Trace], which can be
+// printed in the style of pcre2test's callout output or summarized per
+// pattern item to find where a pattern backtracks the most.
+// Traces of a whole corpus can be aggregated into a [Coverage] report showing
+// which parts of a pattern were never exercised.
+//
+// Tracing is slow and the traces of catastrophically backtracking patterns are
+// huge; use [Debugger.MaxSteps] to bound them.
+package debug
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+// Debugger traces matches of a compiled pattern.
+type Debugger struct {
+	// MaxSteps bounds the number of steps recorded by a single trace. Once
+	// reached, the match is aborted and the trace is marked as truncated. Zero
+	// means no limit.
+	MaxSteps int
+
+	re *pcregexp.PCREgexp
+
+	// Every trace installs its callout on a copy of re of its own, so that
+	// concurrent traces do not see each other's steps.
+	mu   sync.Mutex
+	idle []*pcregexp.PCREgexp
+}
+
+// traceOptions are the compile options added to those of traced patterns.
+const traceOptions = pcregexp.AutoCallout | pcregexp.NoAutoPossess | pcregexp.NoStartOptimize
+
+// Step is the state of a match when PCRE2 was about to match a pattern item.
+type Step struct {
+	// PatternOffset is the offset in the pattern of the next item.
+	PatternOffset int
+
+	// NextItemLength is the length of the next item, 0 at the end of the
+	// pattern.
+	NextItemLength int
+
+	// Start is the subject offset where the current match attempt began.
+	Start int
+
+	// Position is the current subject offset.
+	Position int
+
+	// Captures holds the index pairs captured so far, laid out like the
+	// result of [pcregexp.PCREgexp.FindSubmatchIndex].
+	Captures []int
+
+	// StartMatch reports whether this is the first step of a match attempt.
+	StartMatch bool
+
+	// Backtrack reports whether PCRE2 backtracked since the previous step.
+	Backtrack bool
+}
+
+// Trace is the record of matching a pattern against a subject.
+type Trace struct {
+	// Pattern is the traced pattern.
+	Pattern string
+
+	// Subject is the subject the pattern was matched against.
+	Subject string
+
+	// Steps holds every step of the match, in order.
+	Steps []Step
+
+	// Match holds the index pairs of the match and its submatches, or nil if
+	// the pattern did not match.
+	Match []int
+
+	// Truncated reports whether the match was aborted after MaxSteps steps.
+	Truncated bool
+}
+
+// ItemStats aggregates the steps taken at one item of a pattern.
+type ItemStats struct {
+	// PatternOffset is the offset of the item in the pattern.
+	PatternOffset int
+
+	// Item is the text of the item.
+	Item string
+
+	// Steps is the number of times PCRE2 was about to match the item.
+	Steps int
+
+	// Backtracks is the number of times matching resumed at the item after a
+	// backtrack.
+	Backtracks int
+}
+
+// Compile compiles pattern for tracing with the given compile options, to
+// which [pcregexp.AutoCallout], [pcregexp.NoAutoPossess] and
+// [pcregexp.NoStartOptimize] are added.
+func Compile(pattern string, opts pcregexp.Option) (*Debugger, error) {
+	re, err := pcregexp.CompileWithOptions(pattern, opts|traceOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Debugger{re: re, idle: []*pcregexp.PCREgexp{re}}, nil
+}
+
+// MustCompile is like Compile but panics on error.
+func MustCompile(pattern string, opts pcregexp.Option) *Debugger {
+	d, err := Compile(pattern, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// Close frees the resources associated with the debugger.
+func (d *Debugger) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, re := range d.idle {
+		if re != d.re {
+			re.Close()
+		}
+	}
+	d.idle = nil
+
+	d.re.Close()
+}
+
+// get returns a compiled copy of the pattern no other trace is using.
+func (d *Debugger) get() (*pcregexp.PCREgexp, error) {
+	d.mu.Lock()
+	if n := len(d.idle); n > 0 {
+		re := d.idle[n-1]
+		d.idle = d.idle[:n-1]
+		d.mu.Unlock()
+
+		return re, nil
+	}
+	d.mu.Unlock()
+
+	return pcregexp.CompileWithOptions(d.re.String(), d.re.Options())
+}
+
+// put hands re back for reuse by get.
+func (d *Debugger) put(re *pcregexp.PCREgexp) {
+	d.mu.Lock()
+	d.idle = append(d.idle, re)
+	d.mu.Unlock()
+}
+
+// Trace matches the pattern against subject, like
+// [pcregexp.PCREgexp.FindSubmatchIndex], and records every step. It is safe
+// for concurrent use. The trace has no steps and no match if no copy of the
+// pattern can be compiled for it, which only happens once the limit set with
+// [pcregexp.SetMemoryLimit] is reached.
+func (d *Debugger) Trace(subject []byte) *Trace {
+	t := &Trace{Pattern: d.re.String(), Subject: string(subject)}
+
+	re, err := d.get()
+	if err != nil {
+		return t
+	}
+	defer d.put(re)
+
+	re.SetCallout(func(c *pcregexp.Callout) int {
+		if d.MaxSteps > 0 && len(t.Steps) >= d.MaxSteps {
+			t.Truncated = true
+			return pcregexp.CalloutAbort
+		}
+
+		t.Steps = append(t.Steps, Step{
+			PatternOffset:  c.PatternPosition,
+			NextItemLength: c.NextItemLength,
+			Start:          c.Start,
+			Position:       c.Position,
+			Captures:       c.Offsets,
+			StartMatch:     c.Flags&pcregexp.CalloutStartMatch != 0,
+			Backtrack:      c.Flags&pcregexp.CalloutBacktrack != 0,
+		})
+
+		return pcregexp.CalloutContinue
+	})
+	defer re.SetCallout(nil)
+
+	if match := re.FindSubmatchIndex(subject); match != nil {
+		t.Match = append([]int(nil), match...)
+	}
+
+	return t
+}
+
+// TraceString is like Trace but matches against a string.
+func (d *Debugger) TraceString(s string) *Trace {
+	return d.Trace([]byte(s))
+}
+
+// Item returns the text of the pattern item s was about to match.
+func (t *Trace) Item(s Step) string {
+	end := s.PatternOffset + s.NextItemLength
+	if s.PatternOffset < 0 || end > len(t.Pattern) {
+		return ""
+	}
+
+	return t.Pattern[s.PatternOffset:end]
+}
+
+// String returns the trace formatted like the callout output of pcre2test.
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.WriteTo(&b)
+
+	return b.String()
+}
+
+// WriteTo writes the trace to w, formatted like the callout output of
+// pcre2test: the subject, one line per step marking the start of the match
+// attempt and the current position, and the outcome.
+func (t *Trace) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	b.WriteString("--->")
+	b.WriteString(printable(t.Subject))
+	b.WriteByte('\n')
+
+	for _, s := range t.Steps {
+		marker := []byte(strings.Repeat(" ", len(t.Subject)+1))
+		if s.Start >= 0 && s.Start < len(marker) {
+			marker[s.Start] = '^'
+		}
+		if s.Position >= 0 && s.Position < len(marker) {
+			marker[s.Position] = '^'
+		}
+
+		line := fmt.Sprintf("%3s %s    %s", fmt.Sprintf("+%d", s.PatternOffset), marker, t.Item(s))
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteByte('\n')
+	}
+
+	switch {
+	case t.Truncated:
+		fmt.Fprintf(&b, "Trace truncated after %d steps\n", len(t.Steps))
+	case t.Match == nil:
+		b.WriteString("No match\n")
+	default:
+		for i := 0; i+1 < len(t.Match); i += 2 {
+			if t.Match[i] < 0 {
+				fmt.Fprintf(&b, "%2d: <unset>\n", i/2)
+				continue
+			}
+			fmt.Fprintf(&b, "%2d: %s\n", i/2, printable(t.Subject[t.Match[i]:t.Match[i+1]]))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// Summary aggregates the steps of the trace per pattern item, ordered by
+// pattern offset.
+func (t *Trace) Summary() []ItemStats {
+	byOffset := make(map[int]*ItemStats)
+
+	for _, s := range t.Steps {
+		st, ok := byOffset[s.PatternOffset]
+		if !ok {
+			st = &ItemStats{PatternOffset: s.PatternOffset, Item: t.Item(s)}
+			byOffset[s.PatternOffset] = st
+		}
+
+		st.Steps++
+		if s.Backtrack {
+			st.Backtracks++
+		}
+	}
+
+	stats := make([]ItemStats, 0, len(byOffset))
+	for _, st := range byOffset {
+		stats = append(stats, *st)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].PatternOffset < stats[j].PatternOffset
+	})
+
+	return stats
+}
+
+// Hotspots returns at most n pattern items with the most backtracks, ties
+// broken by the number of steps. If n < 0, all items that were backtracked
+// into are returned.
+func (t *Trace) Hotspots(n int) []ItemStats {
+	var stats []ItemStats
+	for _, st := range t.Summary() {
+		if st.Backtracks > 0 {
+			stats = append(stats, st)
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Backtracks != stats[j].Backtracks {
+			return stats[i].Backtracks > stats[j].Backtracks
+		}
+
+		return stats[i].Steps > stats[j].Steps
+	})
+
+	if n >= 0 && n < len(stats) {
+		stats = stats[:n]
+	}
+
+	return stats
+}
+
+// printable replaces control and non-ASCII bytes of s with dots, so that one
+// byte takes one column and the step markers line up.
+func printable(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c < 0x20 || c >= 0x7f {
+			b[i] = '.'
+		}
+	}
+
+	return string(b)
+}
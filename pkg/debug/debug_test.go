@@ -0,0 +1,104 @@
+package debug
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	d := MustCompile(`a+b`, 0)
+	defer d.Close()
+
+	tr := d.TraceString("xaab")
+
+	if want := []int{1, 4}; !reflect.DeepEqual(tr.Match, want) {
+		t.Errorf("Match = %v, want %v", tr.Match, want)
+	}
+
+	want := strings.Join([]string{
+		"--->xaab",
+		" +0 ^        a+",
+		" +0  ^       a+",
+		" +2  ^ ^     b",
+		" +3  ^  ^",
+		" 0: aab",
+		"",
+	}, "\n")
+	if got := tr.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	wantSummary := []ItemStats{
+		{PatternOffset: 0, Item: "a+", Steps: 2, Backtracks: 1},
+		{PatternOffset: 2, Item: "b", Steps: 1},
+		{PatternOffset: 3, Item: "", Steps: 1},
+	}
+	if got := tr.Summary(); !reflect.DeepEqual(got, wantSummary) {
+		t.Errorf("Summary() = %+v, want %+v", got, wantSummary)
+	}
+}
+
+func TestTrace_NoMatch(t *testing.T) {
+	d := MustCompile(`ab`, 0)
+	defer d.Close()
+
+	tr := d.TraceString("ac")
+	if tr.Match != nil {
+		t.Errorf("Match = %v, want nil", tr.Match)
+	}
+
+	if !strings.HasSuffix(tr.String(), "No match\n") {
+		t.Errorf("String() = %q, want suffix %q", tr.String(), "No match\n")
+	}
+}
+
+func TestTrace_Hotspots(t *testing.T) {
+	d := MustCompile(`(a+)+$`, 0)
+	defer d.Close()
+	d.MaxSteps = 50
+
+	tr := d.TraceString("aaaaab")
+	if !tr.Truncated {
+		t.Fatalf("Truncated = false, want true")
+	}
+
+	if len(tr.Steps) != d.MaxSteps {
+		t.Errorf("len(Steps) = %d, want %d", len(tr.Steps), d.MaxSteps)
+	}
+
+	hot := tr.Hotspots(1)
+	if len(hot) != 1 || hot[0].Item != "$" {
+		t.Errorf("Hotspots(1) = %+v, want the $ item first", hot)
+	}
+}
+
+func TestTrace_Concurrent(t *testing.T) {
+	d := MustCompile(`(\w+)@(\w+)`, 0)
+	defer d.Close()
+
+	subjects := []string{"a@b", "hello world@example", "no match here", "x y@z"}
+
+	want := make([]string, len(subjects))
+	for i, s := range subjects {
+		want[i] = d.TraceString(s).String()
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				k := (g + i) % len(subjects)
+				if got := d.TraceString(subjects[k]).String(); got != want[k] {
+					t.Errorf("TraceString(%q) =\n%s\nwant\n%s", subjects[k], got, want[k])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,135 @@
+package pcregexp
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Position is the location of a byte offset in a text.
+type Position struct {
+	// Offset is the byte offset.
+	Offset int
+
+	// Rune is the number of runes before Offset.
+	Rune int
+
+	// Line is the line number, starting at 1. Lines end with '\n'.
+	Line int
+
+	// Column is the number of runes before Offset on its line, plus 1.
+	Column int
+}
+
+// Positions returns the positions in s of the offsets of matches, as returned
+// by [PCREgexp.FindAllStringIndex] or [PCREgexp.FindAllStringSubmatchIndex],
+// in the same layout. Offsets of -1, for subexpressions that did not
+// participate in a match, yield positions whose fields are all -1.
+//
+// The positions are found in a single pass over s, however many matches
+// there are. An offset in the middle of a rune, or of an invalid UTF-8
+// sequence, is after one rune per byte of it, as with
+// utf8.RuneCountInString(s[:offset]).
+func Positions(s string, matches [][]int) [][]Position {
+	var offsets []int
+	for _, match := range matches {
+		offsets = append(offsets, match...)
+	}
+
+	located := locate(s, offsets)
+	positions := make([][]Position, len(matches))
+
+	for i, match := range matches {
+		positions[i], located = located[:len(match):len(match)], located[len(match):]
+	}
+
+	return positions
+}
+
+// locate returns the positions in s of offsets, which need not be sorted.
+func locate(s string, offsets []int) []Position {
+	positions := make([]Position, len(offsets))
+	order := make([]int, 0, len(offsets))
+
+	for i, off := range offsets {
+		if off < 0 {
+			positions[i] = Position{Offset: -1, Rune: -1, Line: -1, Column: -1}
+			continue
+		}
+
+		order = append(order, i)
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return offsets[order[a]] < offsets[order[b]]
+	})
+
+	// Walk s once, stopping at every offset in turn. runes and lineStart
+	// count the runes before i and before the current line.
+	i, runes, line, lineStart := 0, 0, 1, 0
+
+	for _, k := range order {
+		off := offsets[k]
+		if off > len(s) {
+			off = len(s)
+		}
+
+		for i < off {
+			r, width := utf8.DecodeRuneInString(s[i:])
+			if i+width > off {
+				break // off is in the middle of this rune
+			}
+
+			i += width
+			runes++
+
+			if r == '\n' {
+				line++
+				lineStart = runes
+			}
+		}
+
+		n := runes + off - i
+		positions[k] = Position{Offset: off, Rune: n, Line: line, Column: n - lineStart + 1}
+	}
+
+	return positions
+}
+
+// runeOffsets replaces the byte offsets in s of offsets with rune offsets, in
+// place, and returns offsets.
+func runeOffsets(s string, offsets []int) []int {
+	for i, p := range locate(s, offsets) {
+		offsets[i] = p.Rune
+	}
+
+	return offsets
+}
+
+// FindStringRuneIndex is like [PCREgexp.FindStringIndex] but returns rune
+// offsets rather than byte offsets.
+func (re *PCREgexp) FindStringRuneIndex(s string) []int {
+	a, ok := re.doMatch(nil, stringToBytesUnsafe(s), 1)
+	if !ok {
+		return nil
+	}
+
+	return runeOffsets(s, a)
+}
+
+// FindStringSubmatchRuneIndex is like [PCREgexp.FindStringSubmatchIndex] but
+// returns rune offsets rather than byte offsets.
+func (re *PCREgexp) FindStringSubmatchRuneIndex(s string) []int {
+	a, ok := re.doMatch(nil, stringToBytesUnsafe(s), -1)
+	if !ok {
+		return nil
+	}
+
+	return runeOffsets(s, a)
+}
+
+// FindAllStringRuneIndex is like [PCREgexp.FindAllStringIndex] but returns
+// rune offsets rather than byte offsets. The offsets of all matches are
+// converted in a single pass over s.
+func (re *PCREgexp) FindAllStringRuneIndex(s string, n int) [][]int {
+	return chunk(runeOffsets(s, re.AppendFindAllStringIndex(nil, s, n)), 2)
+}
@@ -0,0 +1,66 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestFindAllStringRuneIndex(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`\w+`, pcregexp.UTF|pcregexp.UCP)
+	defer re.Close()
+
+	s := "héllo wörld, 日本"
+
+	got := re.FindAllStringRuneIndex(s, -1)
+	if want := [][]int{{0, 5}, {6, 11}, {13, 15}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindAllStringRuneIndex() = %v, want %v", got, want)
+	}
+
+	runes := []rune(s)
+	for i, match := range re.FindAllString(s, -1) {
+		if text := string(runes[got[i][0]:got[i][1]]); text != match {
+			t.Errorf("match %d = %q, want %q", i, text, match)
+		}
+	}
+
+	if got, want := re.FindStringRuneIndex("¿qué?"), []int{1, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindStringRuneIndex() = %v, want %v", got, want)
+	}
+}
+
+func TestFindStringSubmatchRuneIndex(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`(ö+)(x)?(r)`, pcregexp.UTF)
+	defer re.Close()
+
+	got := re.FindStringSubmatchRuneIndex("wöörld")
+	if want := []int{1, 4, 1, 3, -1, -1, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindStringSubmatchRuneIndex() = %v, want %v", got, want)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`(é)|x`, pcregexp.UTF)
+	defer re.Close()
+
+	s := "aé\nxx\n\nbé"
+
+	got := pcregexp.Positions(s, re.FindAllStringSubmatchIndex(s, -1))
+	want := [][]pcregexp.Position{
+		{{1, 1, 1, 2}, {3, 2, 1, 3}, {1, 1, 1, 2}, {3, 2, 1, 3}},
+		{{4, 3, 2, 1}, {5, 4, 2, 2}, {-1, -1, -1, -1}, {-1, -1, -1, -1}},
+		{{5, 4, 2, 2}, {6, 5, 2, 3}, {-1, -1, -1, -1}, {-1, -1, -1, -1}},
+		{{9, 8, 4, 2}, {11, 9, 4, 3}, {9, 8, 4, 2}, {11, 9, 4, 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Positions() = %v, want %v", got, want)
+	}
+
+	// Offsets within a rune count one rune per byte.
+	got = pcregexp.Positions("é", [][]int{{1}})
+	if r := utf8.RuneCountInString("é"[:1]); got[0][0].Rune != r {
+		t.Errorf("Positions() rune = %d, want %d", got[0][0].Rune, r)
+	}
+}
@@ -0,0 +1,393 @@
+package pcregexp
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// prefilter rules out subjects that cannot match without calling into PCRE2,
+// which costs a foreign call even when the subject does not match.
+type prefilter struct {
+	// minLength is the minimum length of a match, as reported by PCRE2.
+	minLength int
+
+	// literal is a string that every match contains, if any.
+	literal []byte
+
+	// complete is set when the pattern matches literal and nothing else, so
+	// that PCRE2 need not be called at all.
+	complete bool
+}
+
+// newPrefilter derives the prefilter of the compiled pattern of re, or returns
+// nil if it has none.
+func newPrefilter(re *PCREgexp) *prefilter {
+	if re.opts&AutoCallout != 0 {
+		// Every step is meant to be observed.
+		return nil
+	}
+
+	p := &prefilter{minLength: int(re.info(pcre2InfoMinLength))}
+
+	lit, complete := requiredLiteral(re.pattern, re.opts)
+	switch {
+	case lit != "":
+		p.literal = []byte(lit)
+		p.complete = complete && !re.opts.utf() && re.opts&Anchored == 0
+	case !maybeCaseless(re.pattern, re.opts):
+		// Fall back to the code units PCRE2 found every match to hold. They
+		// are meaningless to us when they may match in either case.
+		if re.info(pcre2InfoFirstCodeType) == 1 {
+			p.literal = []byte{byte(re.info(pcre2InfoFirstCodeUnit))}
+		} else if re.info(pcre2InfoLastCodeType) == 1 {
+			p.literal = []byte{byte(re.info(pcre2InfoLastCodeUnit))}
+		}
+	}
+
+	if p.minLength == 0 && p.literal == nil {
+		return nil
+	}
+
+	return p
+}
+
+// rejects reports whether subject cannot match.
+func (p *prefilter) rejects(subject []byte) bool {
+	return len(subject) < p.minLength || !bytes.Contains(subject, p.literal)
+}
+
+// requiredLiteral returns the longest literal every match of pattern contains,
+// and whether the pattern is that literal and nothing else. The literal is
+// empty when the analysis cannot tell.
+//
+// Only the top level of the pattern is analyzed: groups, classes, escapes
+// other than quoted punctuation and quantified atoms merely end a literal.
+func requiredLiteral(pattern string, opts Option) (string, bool) {
+	// Callout strings may hold any character, parentheses included.
+	if opts&(Caseless|Extended) != 0 || strings.Contains(pattern, "(?C") {
+		return "", false
+	}
+
+	var best, run []byte
+	complete := true
+	atom := 0 // length of the last literal atom in run, 0 if none
+
+	flush := func() {
+		if len(run) > len(best) {
+			best = run
+		}
+		run, atom = nil, 0
+	}
+
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+
+		switch c {
+		case '\\':
+			if i+1 == len(pattern) {
+				return "", false
+			}
+
+			e := pattern[i+1]
+			switch {
+			case e == 'Q':
+				quoted := pattern[i+2:]
+				i = len(pattern)
+				if j := strings.Index(quoted, `\E`); j >= 0 {
+					quoted, i = quoted[:j], i-len(quoted)+j+2
+				}
+
+				if quoted != "" {
+					_, atom = utf8.DecodeLastRuneInString(quoted)
+					run = append(run, quoted...)
+				}
+			case isAlnum(e):
+				flush()
+				complete = false
+				i = skipEscape(pattern, i)
+			default:
+				n := 1
+				if e >= utf8.RuneSelf && opts.utf() {
+					_, n = utf8.DecodeRuneInString(pattern[i+1:])
+				}
+
+				run = append(run, pattern[i+1:i+1+n]...)
+				atom = n
+				i += 1 + n
+			}
+		case '[':
+			flush()
+			complete = false
+			if i = skipClass(pattern, i); i < 0 {
+				return "", false
+			}
+		case '(':
+			// Options set for the rest of the pattern may change how
+			// literals match.
+			flags, scoped := groupFlags(pattern[i:])
+			if strings.HasPrefix(pattern[i:], "(*") || (!scoped && strings.ContainsAny(flags, "ix")) {
+				return "", false
+			}
+
+			flush()
+			complete = false
+			if i = skipGroup(pattern, i); i < 0 {
+				return "", false
+			}
+		case '*', '+', '?', '{':
+			min, n := quantifier(pattern[i:])
+			if n == 0 {
+				return "", false
+			}
+
+			if min == 0 {
+				run = run[:len(run)-atom]
+			}
+			flush()
+			complete = false
+			i += n
+		case '|', ')':
+			return "", false
+		case '.', '^', '$':
+			flush()
+			complete = false
+			i++
+		default:
+			n := 1
+			if c >= utf8.RuneSelf && opts.utf() {
+				_, n = utf8.DecodeRuneInString(pattern[i:])
+			}
+
+			run = append(run, pattern[i:i+n]...)
+			atom = n
+			i += n
+		}
+	}
+	flush()
+
+	return string(best), complete && len(best) > 0
+}
+
+// quantifier parses the quantifier at the start of s, returning its minimum
+// and length, including a lazy or possessive suffix. The length is 0 if s
+// does not start with a quantifier.
+func quantifier(s string) (min, n int) {
+	switch s[0] {
+	case '*', '?':
+		n = 1
+	case '+':
+		min, n = 1, 1
+	case '{':
+		j := strings.IndexByte(s, '}')
+		if j < 0 {
+			return 0, 0
+		}
+
+		bounds := strings.SplitN(s[1:j], ",", 2)
+		if bounds[0] == "" || !isDigits(bounds[0]) || (len(bounds) == 2 && !isDigits(bounds[1])) {
+			return 0, 0
+		}
+
+		if strings.Trim(bounds[0], "0") != "" {
+			min = 1 // all that matters is whether it is zero
+		}
+		n = j + 1
+	}
+
+	if n < len(s) && (s[n] == '?' || s[n] == '+') {
+		n++
+	}
+
+	return min, n
+}
+
+// skipEscape returns the offset following the escape sequence at pattern[i].
+func skipEscape(pattern string, i int) int {
+	e := pattern[i+1]
+	i += 2
+
+	if i == len(pattern) {
+		return i
+	}
+
+	switch {
+	case e == 'c':
+		return i + 1
+	case pattern[i] == '{' && strings.IndexByte("xopPNgku", e) >= 0:
+		if j := strings.IndexByte(pattern[i:], '}'); j >= 0 {
+			return i + j + 1
+		}
+
+		return len(pattern)
+	case (e == 'k' || e == 'g') && (pattern[i] == '<' || pattern[i] == '\''):
+		end := byte('>')
+		if pattern[i] == '\'' {
+			end = '\''
+		}
+
+		if j := strings.IndexByte(pattern[i+1:], end); j >= 0 {
+			return i + j + 2
+		}
+
+		return len(pattern)
+	case e == 'p' || e == 'P':
+		i++
+	case e == 'x':
+		for n := 0; n < 2 && i < len(pattern) && isHex(pattern[i]); n++ {
+			i++
+		}
+	case e == 'g' || isDigit(e):
+		// A back reference or an octal escape.
+		if pattern[i] == '-' || pattern[i] == '+' {
+			i++
+		}
+		for i < len(pattern) && isDigit(pattern[i]) {
+			i++
+		}
+	}
+
+	return i
+}
+
+// skipClass returns the offset following the character class at pattern[i],
+// or -1 if it is not terminated.
+func skipClass(pattern string, i int) int {
+	i++
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	if i < len(pattern) && pattern[i] == ']' {
+		i++ // a leading ] is literal
+	}
+
+	for i < len(pattern) {
+		switch {
+		case strings.HasPrefix(pattern[i:], `\Q`):
+			j := strings.Index(pattern[i:], `\E`)
+			if j < 0 {
+				return -1
+			}
+			i += j + 2
+		case pattern[i] == '\\':
+			i += 2
+		case strings.HasPrefix(pattern[i:], "[:"):
+			j := strings.Index(pattern[i:], ":]")
+			if j < 0 {
+				i++
+				continue
+			}
+			i += j + 2
+		case pattern[i] == ']':
+			return i + 1
+		default:
+			i++
+		}
+	}
+
+	return -1
+}
+
+// skipGroup returns the offset following the group at pattern[i], or -1 if it
+// is not terminated.
+func skipGroup(pattern string, i int) int {
+	depth := 0
+
+	for i < len(pattern) {
+		switch {
+		case strings.HasPrefix(pattern[i:], "(?#"):
+			j := strings.IndexByte(pattern[i:], ')')
+			if j < 0 {
+				return -1
+			}
+			i += j + 1
+			if depth == 0 {
+				return i
+			}
+		case strings.HasPrefix(pattern[i:], `\Q`):
+			j := strings.Index(pattern[i:], `\E`)
+			if j < 0 {
+				return -1
+			}
+			i += j + 2
+		case pattern[i] == '\\':
+			i += 2
+		case pattern[i] == '[':
+			if i = skipClass(pattern, i); i < 0 {
+				return -1
+			}
+		case pattern[i] == '(':
+			depth++
+			i++
+		case pattern[i] == ')':
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+
+	return -1
+}
+
+// groupFlags returns the option letters set or unset by a group such as (?i)
+// or (?x-s:...) at the start of s, and whether they only apply within the
+// group. It returns an empty string for other groups.
+func groupFlags(s string) (flags string, scoped bool) {
+	if !strings.HasPrefix(s, "(?") {
+		return "", false
+	}
+
+	for i := 2; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ')':
+			return s[2:i], false
+		case c == ':':
+			return s[2:i], true
+		case c == '-' || c == '^' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+// maybeCaseless reports whether any part of the pattern may match letters in
+// either case.
+func maybeCaseless(pattern string, opts Option) bool {
+	if opts&Caseless != 0 {
+		return true
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '(' {
+			continue
+		}
+
+		if flags, _ := groupFlags(pattern[i:]); strings.IndexByte(flags, 'i') >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHex(c byte) bool { return isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'f') }
+
+func isAlnum(c byte) bool { return isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z') }
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
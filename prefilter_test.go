@@ -0,0 +1,104 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestInfo(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		opts      pcregexp.Option
+		prefilter string
+		literal   bool
+	}{
+		{`hello`, 0, "hello", true},
+		{`foo\.bar`, 0, "foo.bar", true},
+		{`caf\Q.*\E`, 0, "caf.*", true},
+		{`hello`, pcregexp.Anchored, "hello", false},
+		{`ab+c`, 0, "ab", false},
+		{`ab?cd`, 0, "cd", false},
+		{`x\d+yyyy`, 0, "yyyy", false},
+		{`\x41BC`, 0, "BC", false},
+		{`\p{L}xyz`, 0, "xyz", false},
+		{`a{0,3}bcd`, 0, "bcd", false},
+		{`(?<=foo)barbaz`, 0, "barbaz", false},
+		{`.*needle.*`, 0, "needle", false},
+		{`\d+x`, 0, "x", false}, // last code unit
+		{`(?i)abc`, 0, "", false},
+		{`abc`, pcregexp.Caseless, "", false},
+		{`a(?i:b)c`, 0, "a", false},
+		{`a|b`, 0, "", false},
+		{`\d+`, 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re := pcregexp.MustCompileWithOptions(tt.pattern, tt.opts)
+			defer re.Close()
+
+			info := re.Info()
+			if info.Prefilter != tt.prefilter || info.Literal != tt.literal {
+				t.Errorf("Info() prefilter = %q, literal = %v, want %q, %v", info.Prefilter, info.Literal, tt.prefilter, tt.literal)
+			}
+
+			if info.Pattern != tt.pattern || info.Options != tt.opts {
+				t.Errorf("Info() = %+v, want pattern %q and options %#x", info, tt.pattern, tt.opts)
+			}
+		})
+	}
+}
+
+func TestInfo_CodeUnits(t *testing.T) {
+	re := pcregexp.MustCompile(`(a)b+c`)
+	defer re.Close()
+
+	info := re.Info()
+	if info.NumSubexp != 1 || info.MinLength != 3 || info.FirstCodeUnit != 'a' || info.LastCodeUnit != 'c' {
+		t.Errorf("Info() = %+v", info)
+	}
+}
+
+// TestPrefilter checks that prefiltered and literal patterns match as they
+// would without the prefilter.
+func TestPrefilter(t *testing.T) {
+	patterns := []string{`hello`, `foo\.bar`, `ab+c`, `ab?cd`, `x\d+y`, `.*needle`, `\bword\b`, `[a-z]+@x`}
+	subjects := []string{"", "hello", "say hello, hello", "foo.bar", "fooxbar", "abbbc ac abc", "acd abcd", "x12y x y", "haystack needle", "a word, words", "me@x you@y"}
+
+	for _, pattern := range patterns {
+		re := pcregexp.MustCompile(pattern)
+		defer re.Close()
+		std := regexp.MustCompile(pattern)
+
+		for _, s := range subjects {
+			if got, want := re.MatchString(s), std.MatchString(s); got != want {
+				t.Errorf("%q.MatchString(%q) = %v, want %v", pattern, s, got, want)
+			}
+
+			if got, want := re.FindAllStringIndex(s, -1), std.FindAllStringIndex(s, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%q.FindAllStringIndex(%q) = %v, want %v", pattern, s, got, want)
+			}
+		}
+	}
+}
+
+func TestPrefilter_Callout(t *testing.T) {
+	re := pcregexp.MustCompile(`(?C1)needle`)
+	defer re.Close()
+
+	called := false
+	if err := re.SetCallout(func(c *pcregexp.Callout) int {
+		called = true
+		return pcregexp.CalloutContinue
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	re.MatchString("nee dle")
+	if !called {
+		t.Error("callout was not called on a subject without the literal")
+	}
+}
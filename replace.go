@@ -0,0 +1,97 @@
+package pcregexp
+
+// Match describes a match passed to the function of
+// [PCREgexp.ReplaceAllSubmatchFunc]. It is only valid during the call.
+type Match struct {
+	// Subject is the text being searched.
+	Subject string
+
+	// Index is the number of the match among the successive matches of the
+	// regexp in Subject, starting at 0.
+	Index int
+
+	// Offsets holds the index pairs of the match and of its subexpressions,
+	// laid out like the result of [PCREgexp.FindStringSubmatchIndex]. Unset
+	// subexpressions are reported as -1.
+	Offsets []int
+}
+
+// Start returns the offset of the start of the match in Subject.
+func (m *Match) Start() int { return m.Offsets[0] }
+
+// End returns the offset of the end of the match in Subject.
+func (m *Match) End() int { return m.Offsets[1] }
+
+// Text returns the text of the match.
+func (m *Match) Text() string { return m.Subject[m.Offsets[0]:m.Offsets[1]] }
+
+// NumGroups returns the number of subexpressions of the regexp.
+func (m *Match) NumGroups() int { return len(m.Offsets)/2 - 1 }
+
+// Group returns the text of the i-th subexpression, or of the whole match if i
+// is 0. It returns "" for subexpressions that did not participate in the
+// match, or that do not exist.
+func (m *Match) Group(i int) string {
+	if i < 0 || 2*i+1 >= len(m.Offsets) || m.Offsets[2*i] < 0 {
+		return ""
+	}
+
+	return m.Subject[m.Offsets[2*i]:m.Offsets[2*i+1]]
+}
+
+// ReplaceN returns a copy of src in which the first n successive matches of
+// the regexp have been replaced by repl, or all of them if n < 0. As with
+// [PCREgexp.ReplaceAll], repl is used literally.
+func (re *PCREgexp) ReplaceN(src, repl []byte, n int) []byte {
+	return re.replace(make([]byte, 0, len(src)), src, n, func(dst []byte, _ []int) ([]byte, bool) {
+		return append(dst, repl...), true
+	})
+}
+
+// ReplaceNString is like [PCREgexp.ReplaceN] but works on strings.
+func (re *PCREgexp) ReplaceNString(src, repl string, n int) string {
+	b := re.replace(make([]byte, 0, len(src)), stringToBytesUnsafe(src), n, func(dst []byte, _ []int) ([]byte, bool) {
+		return append(dst, repl...), true
+	})
+
+	return bytesToStringUnsafe(b)
+}
+
+// ReplaceFirst returns a copy of src in which the leftmost match of the
+// regexp, if any, has been replaced by repl.
+func (re *PCREgexp) ReplaceFirst(src, repl []byte) []byte {
+	return re.ReplaceN(src, repl, 1)
+}
+
+// ReplaceFirstString is like [PCREgexp.ReplaceFirst] but works on strings.
+func (re *PCREgexp) ReplaceFirstString(src, repl string) string {
+	return re.ReplaceNString(src, repl, 1)
+}
+
+// ReplaceAllSubmatchFunc returns a copy of src in which all matches of the
+// regexp have been replaced by the return value of repl, which is given the
+// offsets of the match and of its subexpressions. If repl returns an error,
+// the replacement stops, and the error is returned along with an empty
+// string.
+func (re *PCREgexp) ReplaceAllSubmatchFunc(src string, repl func(m *Match) (string, error)) (string, error) {
+	var err error
+	m := Match{Subject: src}
+
+	b := re.replace(make([]byte, 0, len(src)), stringToBytesUnsafe(src), -1, func(dst []byte, match []int) ([]byte, bool) {
+		m.Offsets = match
+
+		var s string
+		if s, err = repl(&m); err != nil {
+			return dst, false
+		}
+		m.Index++
+
+		return append(dst, s...), true
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return bytesToStringUnsafe(b), nil
+}
@@ -0,0 +1,112 @@
+package pcregexp_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestReplaceN(t *testing.T) {
+	re := pcregexp.MustCompile(`a+`)
+	defer re.Close()
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{-1, "b-c-d-"},
+		{0, "baacaaadaa"},
+		{1, "b-caaadaa"},
+		{2, "b-c-daa"},
+		{10, "b-c-d-"},
+	}
+
+	for _, tt := range tests {
+		if got := re.ReplaceNString("baacaaadaa", "-", tt.n); got != tt.want {
+			t.Errorf("ReplaceNString(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+
+		if got := string(re.ReplaceN([]byte("baacaaadaa"), []byte("-"), tt.n)); got != tt.want {
+			t.Errorf("ReplaceN(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+
+	if got, want := re.ReplaceFirstString("banana", "o"), "bonana"; got != want {
+		t.Errorf("ReplaceFirstString() = %q, want %q", got, want)
+	}
+
+	if got, want := string(re.ReplaceFirst([]byte("xyz"), []byte("o"))), "xyz"; got != want {
+		t.Errorf("ReplaceFirst() = %q, want %q", got, want)
+	}
+}
+
+func TestReplace_Stdlib(t *testing.T) {
+	patterns := []string{`x*`, `a+`, ``, `l*`, `$`}
+	subjects := []string{"", "banana", "héllo", "日本語"}
+
+	for _, pattern := range patterns {
+		re := pcregexp.MustCompile(pattern)
+		std := regexp.MustCompile(pattern)
+
+		for _, s := range subjects {
+			want := std.ReplaceAllString(s, "-")
+
+			if got := re.ReplaceAllString(s, "-"); got != want {
+				t.Errorf("%q: ReplaceAllString(%q) = %q, want %q", pattern, s, got, want)
+			}
+
+			if got := re.ReplaceNString(s, "-", -1); got != want {
+				t.Errorf("%q: ReplaceNString(%q, -1) = %q, want %q", pattern, s, got, want)
+			}
+		}
+
+		re.Close()
+	}
+}
+
+func TestReplaceAllSubmatchFunc(t *testing.T) {
+	re := pcregexp.MustCompile(`(\w+)=(\d+)?`)
+	defer re.Close()
+
+	got, err := re.ReplaceAllSubmatchFunc("a=1, b=, c=30", func(m *pcregexp.Match) (string, error) {
+		if m.Group(2) == "" {
+			return fmt.Sprintf("%s@%d:unset", m.Group(1), m.Start()), nil
+		}
+
+		n, err := strconv.Atoi(m.Group(2))
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s#%d=%d", m.Group(1), m.Index, 2*n), nil
+	})
+	if err != nil {
+		t.Fatalf("ReplaceAllSubmatchFunc() error = %v", err)
+	}
+
+	if want := "a#0=2, b@5:unset, c#2=60"; got != want {
+		t.Errorf("ReplaceAllSubmatchFunc() = %q, want %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+
+	got, err = re.ReplaceAllSubmatchFunc("a=1, b=2, c=3", func(m *pcregexp.Match) (string, error) {
+		if calls++; m.Text() == "b=2" {
+			return "", errStop
+		}
+
+		return "x", nil
+	})
+	if !errors.Is(err, errStop) || got != "" {
+		t.Errorf("ReplaceAllSubmatchFunc() = %q, %v, want \"\", %v", got, err, errStop)
+	}
+
+	if calls != 2 {
+		t.Errorf("repl called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,120 @@
+package pcregexp
+
+// reverseBlock is the number of matches ReverseMatches finds again at a time,
+// going backwards.
+const reverseBlock = 64
+
+// findLast appends to dst the offsets of the first pairs offset pairs (all of
+// them if pairs < 0) of the last of the successive matches in b, and reports
+// whether there was a match.
+func (re *PCREgexp) findLast(dst []int, b []byte, pairs int) ([]int, bool) {
+	n, found := len(dst), false
+
+	re.allMatches(b, -1, func(match []int) {
+		if pairs >= 0 && 2*pairs < len(match) {
+			match = match[:2*pairs]
+		}
+
+		dst, found = append(dst[:n], match...), true
+	})
+
+	return dst, found
+}
+
+// FindLastIndex returns a two-element slice of integers defining the location
+// of the last of the successive matches of the regexp in b, as returned by
+// [PCREgexp.FindAllIndex]. Only the last match is kept while searching.
+func (re *PCREgexp) FindLastIndex(b []byte) []int {
+	a, ok := re.findLast(nil, b, 1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindLastStringIndex is like [PCREgexp.FindLastIndex] but searches s.
+func (re *PCREgexp) FindLastStringIndex(s string) []int {
+	return re.FindLastIndex(stringToBytesUnsafe(s))
+}
+
+// FindLastSubmatchIndex is like [PCREgexp.FindLastIndex] but also returns the
+// index pairs of the subexpressions, as [PCREgexp.FindSubmatchIndex] does.
+func (re *PCREgexp) FindLastSubmatchIndex(b []byte) []int {
+	a, ok := re.findLast(nil, b, -1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindLastStringSubmatchIndex is like [PCREgexp.FindLastSubmatchIndex] but
+// searches s.
+func (re *PCREgexp) FindLastStringSubmatchIndex(s string) []int {
+	return re.FindLastSubmatchIndex(stringToBytesUnsafe(s))
+}
+
+// ReverseMatches calls fn with the successive matches of the regexp in b, as
+// returned by [PCREgexp.FindAllSubmatchIndex], from the last to the first. It
+// stops early once fn returns false. The offsets are only valid during the
+// call.
+//
+// The matches are not all kept at once: a first pass over b records where
+// the search stood every few matches, and the matches are then found again
+// backwards, a block at a time. The callout function, if any, is therefore
+// called more than once for the same search.
+func (re *PCREgexp) ReverseMatches(b []byte, fn func(match []int) bool) {
+	if re.code == 0 {
+		return
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return
+	}
+	defer re.putMatchData(m)
+
+	st := scanState{prevEnd: -1}
+	checkpoints := []scanState{st}
+	count := 0
+
+	re.scanFrom(m, b, -1, &st, 0, func([]int) bool {
+		if count++; count%reverseBlock == 0 {
+			checkpoints = append(checkpoints, st)
+		}
+
+		return true
+	})
+
+	stride := len(m.ovector)
+	block := make([]int, 0, reverseBlock*stride)
+
+	for k := len(checkpoints) - 1; k >= 0; k-- {
+		size := count - k*reverseBlock
+		if size <= 0 {
+			continue
+		}
+		if size > reverseBlock {
+			size = reverseBlock
+		}
+
+		// The first pass checked b to be valid UTF if need be.
+		st, block = checkpoints[k], block[:0]
+		re.scanFrom(m, b, size, &st, re.utfChecked(), func(match []int) bool {
+			block = append(block, match...)
+			return true
+		})
+
+		for i := size - 1; i >= 0; i-- {
+			if !fn(block[i*stride : (i+1)*stride : (i+1)*stride]) {
+				return
+			}
+		}
+	}
+}
+
+// ReverseStringMatches is like [PCREgexp.ReverseMatches] but searches s.
+func (re *PCREgexp) ReverseStringMatches(s string, fn func(match []int) bool) {
+	re.ReverseMatches(stringToBytesUnsafe(s), fn)
+}
@@ -0,0 +1,81 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestFindLast(t *testing.T) {
+	re := pcregexp.MustCompile(`(\d\d):(\d\d)`)
+	defer re.Close()
+
+	s := "start 09:15, pause 12:30, end 17:45."
+
+	if got, want := re.FindLastStringIndex(s), []int{30, 35}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindLastStringIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := re.FindLastStringSubmatchIndex(s), []int{30, 35, 30, 32, 33, 35}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindLastStringSubmatchIndex() = %v, want %v", got, want)
+	}
+
+	if got := re.FindLastStringIndex("no time"); got != nil {
+		t.Errorf("FindLastStringIndex() = %v, want nil", got)
+	}
+
+	// The last match is the last of the successive matches, not the match
+	// starting the furthest right.
+	aa := pcregexp.MustCompile(`aa`)
+	defer aa.Close()
+
+	if got, want := aa.FindLastStringIndex("aaa"), []int{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindLastStringIndex() = %v, want %v", got, want)
+	}
+}
+
+func TestReverseMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+	}{
+		{`\w+`, "path/to/some/file.txt"},
+		{`x*`, "axxbx"},
+		{`(a)|(b)`, strings.Repeat("ab-", 150)},
+		{`\d+`, "no digits"},
+	}
+
+	for _, tt := range tests {
+		re := pcregexp.MustCompile(tt.pattern)
+
+		want := re.FindAllStringSubmatchIndex(tt.subject, -1)
+		for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+			want[i], want[j] = want[j], want[i]
+		}
+
+		var got [][]int
+		re.ReverseStringMatches(tt.subject, func(match []int) bool {
+			got = append(got, append([]int(nil), match...))
+			return true
+		})
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: ReverseStringMatches() = %v, want %v", tt.pattern, got, want)
+		}
+
+		// Stopping early.
+		calls := 0
+		re.ReverseStringMatches(tt.subject, func([]int) bool {
+			calls++
+			return false
+		})
+
+		if len(want) > 0 && calls != 1 {
+			t.Errorf("%q: fn called %d times, want 1", tt.pattern, calls)
+		}
+
+		re.Close()
+	}
+}
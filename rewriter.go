@@ -0,0 +1,305 @@
+package pcregexp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// Rule is a rewriting rule of a [Rewriter]: the matches of Pattern are
+// replaced by Replacement, a template as accepted by
+// [PCREgexp.CompileTemplate] whose references are to the subexpressions of
+// Pattern, or by the result of Func if it is not nil. With PreserveCase, the
+// expansion of Replacement takes the letter case of the match, as with
+// [Template.PreserveCase]; it does not apply to Func.
+type Rule struct {
+	Pattern      string
+	Replacement  string
+	Func         func(m *Match) string
+	PreserveCase bool
+}
+
+// Rewriter applies a list of rules in a single pass over a text. It is safe
+// for concurrent use by multiple goroutines.
+type Rewriter struct {
+	re    *PCREgexp
+	rules []rewriterRule
+}
+
+// rewriterRule is a compiled Rule.
+type rewriterRule struct {
+	template  *Template
+	fn        func(m *Match) string
+	numSubexp int
+}
+
+// NewRewriter compiles rules, with the options opts, into a [Rewriter].
+//
+// The rules are joined into a single regexp, as alternatives of a branch
+// reset group, (?|...), so that the subexpressions of each rule keep their
+// numbers, and back references and templates work as for the rule alone.
+// Each alternative ends with (*MARK:i), i being the index of the rule, which
+// tells the rule that matched. Subexpression names must thus be given to the
+// same numbers across rules, and a rule ending with (*ACCEPT) or setting marks
+// after its own end is not supported. Neither are subroutine calls and
+// recursion, such as (?1), (?R) or (?&name), whose groups would be those of
+// the joined regexp.
+func NewRewriter(rules []Rule, opts Option) (*Rewriter, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no rewriting rules")
+	}
+
+	w := &Rewriter{rules: make([]rewriterRule, len(rules))}
+	alternatives := make([]string, len(rules))
+
+	for i, rule := range rules {
+		// Compile the rule alone first, for an error pointing at the rule
+		// and for its template.
+		re, err := CompileWithOptions(rule.Pattern, opts)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		w.rules[i] = rewriterRule{fn: rule.Func, numSubexp: re.NumSubexp()}
+		if rule.Func == nil {
+			w.rules[i].template, err = re.CompileTemplate(rule.Replacement)
+			if err == nil && rule.PreserveCase {
+				w.rules[i].template = w.rules[i].template.PreserveCase()
+			}
+		}
+		re.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+
+		if hasSubroutineCall(rule.Pattern, opts) {
+			return nil, fmt.Errorf("rule %d: subroutine calls and recursion are not supported", i)
+		}
+
+		alternatives[i] = fmt.Sprintf("(?:%s%s)(*MARK:%d)", rule.Pattern, ruleEnd(rule.Pattern, opts), i)
+	}
+
+	re, err := CompileWithOptions(joinRules(alternatives), opts)
+	if err != nil {
+		first, last := conflictingRules(alternatives, opts)
+		if first == last {
+			return nil, fmt.Errorf("rule %d cannot be joined: %w", first, err)
+		}
+
+		return nil, fmt.Errorf("rules %d and %d cannot be joined: %w", first, last, err)
+	}
+	w.re = re
+
+	return w, nil
+}
+
+// joinRules returns the regexp joining the alternatives of rules.
+func joinRules(alternatives []string) string {
+	return "(?|" + strings.Join(alternatives, "|") + ")"
+}
+
+// ruleEnd returns what to insert between pattern, which compiles alone, and
+// the parenthesis closing it, so that the parenthesis is not quoted by an
+// unterminated \Q or part of a trailing # comment.
+func ruleEnd(pattern string, opts Option) string {
+	if !strings.Contains(pattern, "#") {
+		return `\E`
+	}
+
+	// A newline ends a comment, but is matched literally elsewhere, unless
+	// the pattern is extended where it ends: only add it if it is needed.
+	re, err := CompileWithOptions("(?:"+pattern+`\E)`, opts)
+	if err != nil {
+		return "\\E\n"
+	}
+	re.Close()
+
+	return `\E`
+}
+
+// conflictingRules returns the indexes of two rules whose alternatives,
+// which compile alone, do not compile once joined, such as rules giving
+// different names to the same subexpression number.
+func conflictingRules(alternatives []string, opts Option) (first, last int) {
+	fails := func(alternatives []string) bool {
+		re, err := CompileWithOptions(joinRules(alternatives), opts)
+		if err != nil {
+			return true
+		}
+		re.Close()
+
+		return false
+	}
+
+	// The shortest failing prefix ends with the last rule, and the
+	// shortest failing suffix of it starts with the first.
+	last = sort.Search(len(alternatives), func(i int) bool {
+		return fails(alternatives[:i+1])
+	})
+	if last == len(alternatives) {
+		return 0, len(alternatives) - 1
+	}
+
+	first = sort.Search(last+1, func(i int) bool {
+		return !fails(alternatives[i : last+1])
+	}) - 1
+	if first < 0 {
+		first = 0
+	}
+
+	return first, last
+}
+
+// hasSubroutineCall reports whether pattern calls a subexpression as a
+// subroutine, by number, relative number, name or with (?R). It skips
+// escapes, quoted text, character classes and comments.
+func hasSubroutineCall(pattern string, opts Option) bool {
+	extended := opts&Extended != 0
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			switch pattern[i] {
+			case 'Q':
+				end := strings.Index(pattern[i:], `\E`)
+				if end < 0 {
+					return false
+				}
+				i += end + 1
+			case 'g':
+				// \g<name> and \g'name' are calls, unlike \g{name} and \gN.
+				if i+1 < len(pattern) && (pattern[i+1] == '<' || pattern[i+1] == '\'') {
+					return true
+				}
+			}
+		case c == '[':
+			end := skipClass(pattern, i)
+			if end < 0 {
+				return false
+			}
+			i = end - 1
+		case c == '#' && extended:
+			end := strings.IndexByte(pattern[i:], '\n')
+			if end < 0 {
+				return false
+			}
+			i += end
+		case c == '(' && strings.HasPrefix(pattern[i:], "(?#"):
+			end := strings.IndexByte(pattern[i:], ')')
+			if end < 0 {
+				return false
+			}
+			i += end
+		case c == '(' && strings.HasPrefix(pattern[i:], "(?"):
+			rest := pattern[i+2:]
+			if strings.HasPrefix(rest, "R)") || strings.HasPrefix(rest, "&") || strings.HasPrefix(rest, "P>") {
+				return true
+			}
+			if len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+				rest = rest[1:]
+			}
+			if len(rest) > 0 && isDigit(rest[0]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MustNewRewriter is like NewRewriter but panics on error.
+func MustNewRewriter(rules []Rule, opts Option) *Rewriter {
+	w, err := NewRewriter(rules, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	return w
+}
+
+// Close frees the resources of the rewriter.
+func (w *Rewriter) Close() {
+	w.re.Close()
+}
+
+// String returns the source text of the regexp joining the rules.
+func (w *Rewriter) String() string {
+	return w.re.String()
+}
+
+// Rewrite returns a copy of src in which the matches of the rules have been
+// replaced. The text is searched once from left to right: at each offset, the
+// first rule that matches there wins, and its replacement is not searched
+// again. This differs from applying the rules one after the other, as with
+// successive calls to [PCREgexp.ReplaceAllString], when the replacement of a
+// rule is matched by a later one.
+//
+// The [Match] passed to the function of a rule has the offsets of the rule's
+// own subexpressions, and its Index counts the matches of all rules.
+func (w *Rewriter) Rewrite(src []byte) []byte {
+	re := w.re
+	dst := make([]byte, 0, len(src))
+
+	if re.code == 0 {
+		return append(dst, src...)
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return append(dst, src...)
+	}
+	defer re.putMatchData(m)
+
+	match := Match{Subject: bytesToStringUnsafe(src)}
+	var scratch []byte
+
+	return re.replaceWith(m, dst, src, -1, func(dst []byte, offsets []int) ([]byte, bool) {
+		rule := w.rule(m)
+		switch {
+		case rule == nil:
+			dst = append(dst, src[offsets[0]:offsets[1]]...)
+		case rule.fn == nil:
+			dst, scratch = expand(dst, scratch, rule.template, src, offsets[:2*(rule.numSubexp+1)])
+		default:
+			match.Offsets = offsets[:2*(rule.numSubexp+1)]
+			dst = append(dst, rule.fn(&match)...)
+		}
+		match.Index++
+
+		return dst, true
+	})
+}
+
+// RewriteString is like [Rewriter.Rewrite] but works on strings.
+func (w *Rewriter) RewriteString(src string) string {
+	return bytesToStringUnsafe(w.Rewrite(stringToBytesUnsafe(src)))
+}
+
+// rule returns the rule of the last match in m, from its mark, or nil if it
+// has none.
+func (w *Rewriter) rule(m *matchData) *rewriterRule {
+	mark := pcre2_get_mark(m.handle)
+	if mark == nil {
+		return nil
+	}
+
+	// The mark is a NUL-terminated rule index.
+	i := 0
+	for p := unsafe.Pointer(mark); *(*byte)(p) != 0; p = unsafe.Add(p, 1) {
+		c := *(*byte)(p)
+		if c < '0' || c > '9' || i >= len(w.rules) {
+			return nil
+		}
+		i = 10*i + int(c-'0')
+	}
+
+	if i >= len(w.rules) {
+		return nil
+	}
+
+	return &w.rules[i]
+}
@@ -0,0 +1,164 @@
+package pcregexp_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestRewriter(t *testing.T) {
+	w, err := pcregexp.NewRewriter([]pcregexp.Rule{
+		{Pattern: `(\d+)-(\d+)`, Replacement: "$2..$1"},
+		{Pattern: `(?<word>[a-z])\k<word>`, Replacement: "<${word}>"},
+		{Pattern: `(?i)cat`, Replacement: "dog"},
+		{Pattern: `\d+`, Func: func(m *pcregexp.Match) string {
+			return fmt.Sprintf("#%d:%s", m.Index, m.Text())
+		}},
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The first rule wins over the last one for "1-2", and (?i) does not
+	// leak into the last rule.
+	got := w.RewriteString("1-2 CAT 7 bookkeeper Cat 42")
+	want := "2..1 dog #2:7 b<o><k><e>per dog #7:42"
+	if got != want {
+		t.Errorf("RewriteString() = %q, want %q", got, want)
+	}
+
+	if got := string(w.Rewrite([]byte("nothing"))); got != "nothing" {
+		t.Errorf("Rewrite() = %q, want %q", got, "nothing")
+	}
+}
+
+func TestRewriterSinglePass(t *testing.T) {
+	rules := []pcregexp.Rule{
+		{Pattern: `a`, Replacement: "b"},
+		{Pattern: `b`, Replacement: "a"},
+	}
+
+	w := pcregexp.MustNewRewriter(rules, 0)
+	defer w.Close()
+
+	// Sequential passes would turn everything into "a".
+	if got, want := w.RewriteString("abba"), "baab"; got != want {
+		t.Errorf("RewriteString() = %q, want %q", got, want)
+	}
+}
+
+func TestRewriterMatchesSequential(t *testing.T) {
+	var rules []pcregexp.Rule
+	for i := 0; i < 300; i++ {
+		rules = append(rules, pcregexp.Rule{
+			Pattern:     fmt.Sprintf(`\bw%d\b`, i),
+			Replacement: fmt.Sprintf("W%d", i),
+		})
+	}
+
+	w := pcregexp.MustNewRewriter(rules, 0)
+	defer w.Close()
+
+	var words []string
+	for i := 0; i < 1000; i++ {
+		words = append(words, fmt.Sprintf("w%d", i*7%400))
+	}
+	src := strings.Join(words, " ")
+
+	want := src
+	for _, rule := range rules {
+		re := pcregexp.MustCompile(rule.Pattern)
+		want = re.ReplaceAllString(want, rule.Replacement)
+		re.Close()
+	}
+
+	if got := w.RewriteString(src); got != want {
+		t.Errorf("RewriteString() differs from sequential replacement")
+	}
+}
+
+func TestNewRewriterErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []pcregexp.Rule
+	}{
+		{"none", nil},
+		{"pattern", []pcregexp.Rule{{Pattern: `a`}, {Pattern: `(`}}},
+		{"template", []pcregexp.Rule{{Pattern: `(a)`, Replacement: "$2"}}},
+		// Subroutine calls would refer to the groups of the joined regexp.
+		{"call", []pcregexp.Rule{{Pattern: `(x)`}, {Pattern: `(z)(?1)`}}},
+		{"relative call", []pcregexp.Rule{{Pattern: `(z)(?-1)`}}},
+		{"recursion", []pcregexp.Rule{{Pattern: `a(?R)?b`}}},
+		{"named call", []pcregexp.Rule{{Pattern: `(?<n>z)(?&n)`}}},
+		{"Python call", []pcregexp.Rule{{Pattern: `(?P<n>z)(?P>n)`}}},
+		{"Oniguruma call", []pcregexp.Rule{{Pattern: `(z)\g<1>`}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := pcregexp.NewRewriter(tt.rules, 0); err == nil {
+			t.Errorf("%s: NewRewriter() error = nil", tt.name)
+		}
+	}
+
+	// Only the joined regexp fails to compile: the error tells which rules
+	// cannot go together.
+	_, err := pcregexp.NewRewriter([]pcregexp.Rule{
+		{Pattern: `a`},
+		{Pattern: `(?<x>b)`},
+		{Pattern: `c`},
+		{Pattern: `(?<y>d)`},
+	}, 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "rules 1 and 3 ") {
+		t.Errorf("NewRewriter() error = %v, want one naming rules 1 and 3", err)
+	}
+}
+
+func TestRewriterNoCalls(t *testing.T) {
+	// Look-alikes of subroutine calls that are not.
+	w, err := pcregexp.NewRewriter([]pcregexp.Rule{
+		{Pattern: `\Q(?R)\E`, Replacement: "q"},
+		{Pattern: `[(?1)]+`, Replacement: "c"},
+		{Pattern: `(a)\g{1}\g1(?i)B(?#(?1)`, Replacement: "r"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewRewriter() error = %v", err)
+	}
+	defer w.Close()
+
+	if got, want := w.RewriteString("(?R) aaab ?1"), "q r c"; got != want {
+		t.Errorf("RewriteString() = %q, want %q", got, want)
+	}
+}
+
+func TestRewriterComments(t *testing.T) {
+	w, err := pcregexp.NewRewriter([]pcregexp.Rule{
+		{Pattern: `a b # the first rule`, Replacement: "1"},
+		{Pattern: `c (?-x)d e`, Replacement: "2"},
+		{Pattern: `\Qf`, Replacement: "3"},
+	}, pcregexp.Extended)
+	if err != nil {
+		t.Fatalf("NewRewriter() error = %v", err)
+	}
+	defer w.Close()
+
+	// The comment does not swallow the other rules, and no newline is
+	// added to the rule that is not extended at its end.
+	if got, want := w.RewriteString("ab cd e f"), "1 2 3"; got != want {
+		t.Errorf("RewriteString() = %q, want %q", got, want)
+	}
+}
+
+func TestRewriterPreserveCase(t *testing.T) {
+	w := pcregexp.MustNewRewriter([]pcregexp.Rule{
+		{Pattern: `(?i)colour`, Replacement: "color", PreserveCase: true},
+		{Pattern: `(?i)grey`, Replacement: "gray"},
+	}, 0)
+	defer w.Close()
+
+	if got, want := w.RewriteString("Colour COLOUR colour Grey"), "Color COLOR color gray"; got != want {
+		t.Errorf("RewriteString() = %q, want %q", got, want)
+	}
+}
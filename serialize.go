@@ -0,0 +1,220 @@
+package pcregexp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"unsafe"
+)
+
+// serialMagic starts the output of [SerializePatterns].
+const serialMagic = "PCRX"
+
+// serialVersion is the version of the format written by [SerializePatterns].
+const serialVersion = 1
+
+// ErrBadSerializedData is returned by [DeserializePatterns] when the data was
+// not produced by [SerializePatterns] or is corrupted.
+var ErrBadSerializedData = errors.New("bad serialized pattern data")
+
+// Version returns the version of the loaded PCRE2 library, such as
+// "10.42 2022-12-11".
+func Version() string {
+	n := pcre2_config(pcre2ConfigVersion, nil)
+	if n <= 0 {
+		return ""
+	}
+
+	buf := make([]byte, n)
+	pcre2_config(pcre2ConfigVersion, ptr(&buf[0]))
+
+	return string(buf[:n-1]) // without the terminating NUL
+}
+
+// SerializePatterns encodes compiled patterns with pcre2_serialize_encode, so
+// that they can later be restored by [DeserializePatterns] without compiling
+// them again.
+//
+// Compiled code only loads into the same PCRE2 version on the same
+// architecture, so the sources and options of the patterns are stored
+// alongside it, and used by [DeserializePatterns] to recompile the patterns
+// when the code does not fit the running library.
+func SerializePatterns(res []*PCREgexp) ([]byte, error) {
+	codes := make([]uintptr, len(res))
+	for i, re := range res {
+		if re == nil || re.code == 0 {
+			return nil, fmt.Errorf("pattern %d is closed", i)
+		}
+		codes[i] = re.code
+	}
+
+	var code []byte
+	if len(codes) > 0 {
+		var serialized *uint8
+		var size uint64
+
+		rc := pcre2_serialize_encode(&codes[0], int32(len(codes)), &serialized, &size, 0)
+		if rc < 0 {
+			return nil, fmt.Errorf("pcre2_serialize_encode failed, error code %d", rc)
+		}
+
+		code = make([]byte, size)
+		copy(code, unsafe.Slice(serialized, size))
+		pcre2_serialize_free(serialized)
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString(serialMagic)
+	b.WriteByte(serialVersion)
+	writeSerialString(&b, runtime.GOARCH)
+	writeSerialString(&b, Version())
+
+	binary.Write(&b, binary.LittleEndian, uint32(len(res)))
+	for _, re := range res {
+		binary.Write(&b, binary.LittleEndian, uint32(re.opts))
+		writeSerialString(&b, re.pattern)
+	}
+	writeSerialString(&b, string(code))
+
+	return b.Bytes(), nil
+}
+
+// DeserializePatterns restores patterns encoded by [SerializePatterns], in the
+// same order.
+//
+// If the data was produced by another PCRE2 version or architecture, or PCRE2
+// rejects the compiled code, the patterns are recompiled from their stored
+// sources instead. Patterns restored from compiled code share their native
+// memory statistics, see [PCREgexp.MemStats].
+func DeserializePatterns(data []byte) ([]*PCREgexp, error) {
+	r := bytes.NewReader(data)
+
+	magic := make([]byte, len(serialMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != serialMagic {
+		return nil, ErrBadSerializedData
+	}
+
+	version, err := r.ReadByte()
+	if err != nil || version != serialVersion {
+		return nil, ErrBadSerializedData
+	}
+
+	arch, err := readSerialString(r)
+	if err != nil {
+		return nil, err
+	}
+
+	libVersion, err := readSerialString(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil || uint64(n) > uint64(r.Len()) {
+		return nil, ErrBadSerializedData
+	}
+
+	res := make([]*PCREgexp, n)
+	for i := range res {
+		var opts uint32
+		if err := binary.Read(r, binary.LittleEndian, &opts); err != nil {
+			return nil, ErrBadSerializedData
+		}
+
+		pattern, err := readSerialString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = &PCREgexp{pattern: pattern, opts: Option(opts)}
+	}
+
+	code, err := readSerialString(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return res, nil
+	}
+
+	if arch == runtime.GOARCH && libVersion == Version() && decodePatterns(res, stringToBytesUnsafe(code)) {
+		return res, nil
+	}
+
+	for i, re := range res {
+		compiled, err := CompileWithOptions(re.pattern, re.opts)
+		if err != nil {
+			for _, re := range res[:i] {
+				re.Close()
+			}
+
+			return nil, fmt.Errorf("recompiling pattern %d: %w", i, err)
+		}
+		res[i] = compiled
+	}
+
+	return res, nil
+}
+
+// decodePatterns loads the serialized code of res with pcre2_serialize_decode,
+// reporting whether it succeeded.
+func decodePatterns(res []*PCREgexp, code []byte) bool {
+	if len(code) == 0 {
+		return false
+	}
+
+	mem, err := newMemAccount(int32(len(res)))
+	if err != nil {
+		return false
+	}
+
+	codes := make([]uintptr, len(res))
+
+	rc := pcre2_serialize_decode(&codes[0], int32(len(codes)), &code[0], mem.gctx)
+	if rc != int32(len(codes)) {
+		for _, c := range codes {
+			if c != 0 {
+				pcre2_code_free(c)
+			}
+		}
+
+		for range res {
+			mem.release()
+		}
+
+		return false
+	}
+
+	for i, re := range res {
+		re.code, re.mem = codes[i], mem
+		re.pre = newPrefilter(re)
+	}
+
+	return true
+}
+
+// writeSerialString writes s prefixed with its length.
+func writeSerialString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.LittleEndian, uint64(len(s)))
+	b.WriteString(s)
+}
+
+// readSerialString reads a string written by writeSerialString.
+func readSerialString(r *bytes.Reader) (string, error) {
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil || n > uint64(r.Len()) {
+		return "", ErrBadSerializedData
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", ErrBadSerializedData
+	}
+
+	return string(buf), nil
+}
@@ -0,0 +1,93 @@
+package pcregexp_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestSerializePatterns(t *testing.T) {
+	patterns := []struct {
+		pattern string
+		opts    pcregexp.Option
+	}{
+		{`p([a-z]+)ch`, 0},
+		{`(?<=foo)bar`, 0},
+		{`hello`, pcregexp.Caseless},
+	}
+
+	var res []*pcregexp.PCREgexp
+	for _, p := range patterns {
+		re := pcregexp.MustCompileWithOptions(p.pattern, p.opts)
+		defer re.Close()
+		res = append(res, re)
+	}
+
+	data, err := pcregexp.SerializePatterns(res)
+	if err != nil {
+		t.Fatalf("SerializePatterns() error = %v", err)
+	}
+
+	check := func(t *testing.T, data []byte) {
+		got, err := pcregexp.DeserializePatterns(data)
+		if err != nil {
+			t.Fatalf("DeserializePatterns() error = %v", err)
+		}
+
+		if len(got) != len(patterns) {
+			t.Fatalf("DeserializePatterns() returned %d patterns, want %d", len(got), len(patterns))
+		}
+
+		for i, re := range got {
+			defer re.Close()
+
+			if re.String() != patterns[i].pattern || re.Options() != patterns[i].opts {
+				t.Errorf("pattern %d = %q (%#x), want %q (%#x)", i, re.String(), re.Options(), patterns[i].pattern, patterns[i].opts)
+			}
+		}
+
+		if !got[0].MatchString("peach") || got[0].NumSubexp() != 1 {
+			t.Errorf("pattern 0 does not behave like %q", patterns[0].pattern)
+		}
+		if !got[1].MatchString("foobar") || got[1].MatchString("bazbar") {
+			t.Errorf("pattern 1 does not behave like %q", patterns[1].pattern)
+		}
+		if !got[2].MatchString("HELLO") {
+			t.Errorf("pattern 2 does not behave like %q", patterns[2].pattern)
+		}
+	}
+
+	t.Run("Decode", func(t *testing.T) {
+		check(t, data)
+	})
+
+	t.Run("Recompile", func(t *testing.T) {
+		// Pretend the data comes from another PCRE2 version.
+		stale := append([]byte(nil), data...)
+		stale[4+1+8+len(runtime.GOARCH)+8] ^= 0xff
+
+		check(t, stale)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		data, err := pcregexp.SerializePatterns(nil)
+		if err != nil {
+			t.Fatalf("SerializePatterns(nil) error = %v", err)
+		}
+
+		got, err := pcregexp.DeserializePatterns(data)
+		if err != nil || len(got) != 0 {
+			t.Errorf("DeserializePatterns() = %v, %v, want no patterns", got, err)
+		}
+	})
+
+	t.Run("BadData", func(t *testing.T) {
+		for _, bad := range [][]byte{nil, []byte("nope"), data[:len(data)-1]} {
+			if _, err := pcregexp.DeserializePatterns(bad); !errors.Is(err, pcregexp.ErrBadSerializedData) {
+				t.Errorf("DeserializePatterns(%q) error = %v, want %v", bad, err, pcregexp.ErrBadSerializedData)
+			}
+		}
+	})
+}
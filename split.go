@@ -0,0 +1,157 @@
+package pcregexp
+
+// splitMode tells what split puts between the pieces of text separated by
+// matches of the regexp.
+type splitMode int
+
+const (
+	splitDrop   splitMode = iota // nothing
+	splitAfter                   // nothing, but each piece ends with its match
+	splitKeep                    // the matches
+	splitGroups                  // the subexpression matches
+)
+
+// split returns the offset pairs of the pieces of b separated by up to n-1
+// matches of the regexp (all of them if n < 0), and of what goes between
+// them according to mode. Offsets of -1 stand for subexpressions that did not
+// participate in a match. The n and empty match rules are those of
+// regexp.Regexp.Split in the standard library.
+func (re *PCREgexp) split(b []byte, n int, mode splitMode) []int {
+	if n == 0 {
+		return nil
+	}
+
+	if len(re.pattern) > 0 && len(b) == 0 {
+		return []int{0, 0}
+	}
+
+	pairs := 1
+	if mode == splitGroups {
+		pairs = -1
+	}
+
+	matches, stride := re.appendAll(nil, b, n, pairs)
+	pieces := make([]int, 0, len(matches)+2)
+
+	// sep is where the matches between the last two pieces were appended,
+	// or -1 if there are none.
+	parts, sep := 0, -1
+
+	beg, end := 0, 0
+	for i := 0; i < len(matches); i += stride {
+		if n > 0 && parts == n-1 {
+			break
+		}
+
+		match := matches[i : i+stride]
+		end = match[0]
+
+		if match[1] != 0 {
+			if mode == splitAfter {
+				pieces = append(pieces, beg, match[1])
+			} else {
+				pieces = append(pieces, beg, end)
+			}
+			parts++
+
+			sep = len(pieces)
+			switch mode {
+			case splitKeep:
+				pieces = append(pieces, match[0], match[1])
+			case splitGroups:
+				pieces = append(pieces, match[2:]...)
+			}
+		}
+		beg = match[1]
+	}
+
+	if end != len(b) {
+		pieces = append(pieces, beg, len(b))
+	} else if sep >= 0 {
+		// There is no piece after an empty match at the very end, nor
+		// anything to put before it.
+		pieces = pieces[:sep]
+	}
+
+	return pieces
+}
+
+// splitStrings returns the substrings of s at the offset pairs returned by
+// split, or nil if n is 0.
+func splitStrings(s string, n int, pieces []int) []string {
+	if n == 0 {
+		return nil
+	}
+
+	parts := make([]string, len(pieces)/2)
+	for i := range parts {
+		if pieces[2*i] >= 0 {
+			parts[i] = s[pieces[2*i]:pieces[2*i+1]]
+		}
+	}
+
+	return parts
+}
+
+// splitBytes is splitStrings for a byte slice. Pieces for subexpressions that
+// did not participate in a match are nil.
+func splitBytes(b []byte, n int, pieces []int) [][]byte {
+	if n == 0 {
+		return nil
+	}
+
+	parts := make([][]byte, len(pieces)/2)
+	for i := range parts {
+		if pieces[2*i] >= 0 {
+			parts[i] = b[pieces[2*i]:pieces[2*i+1]:pieces[2*i+1]]
+		}
+	}
+
+	return parts
+}
+
+// SplitBytes is like [PCREgexp.Split] but slices b. The subslices share the
+// memory of b.
+func (re *PCREgexp) SplitBytes(b []byte, n int) [][]byte {
+	return splitBytes(b, n, re.split(b, n, splitDrop))
+}
+
+// SplitAfter is like [PCREgexp.Split], but each substring ends with the match
+// following it, as with strings.SplitAfter. Concatenating the substrings
+// gives s back when n < 0.
+func (re *PCREgexp) SplitAfter(s string, n int) []string {
+	return splitStrings(s, n, re.split(stringToBytesUnsafe(s), n, splitAfter))
+}
+
+// SplitAfterBytes is like [PCREgexp.SplitAfter] but slices b.
+func (re *PCREgexp) SplitAfterBytes(b []byte, n int) [][]byte {
+	return splitBytes(b, n, re.split(b, n, splitAfter))
+}
+
+// SplitKeep is like [PCREgexp.Split], but the matches separating the
+// substrings are kept in between them: the result alternates substrings and
+// matches, starting and ending with a substring. n counts the substrings
+// only.
+func (re *PCREgexp) SplitKeep(s string, n int) []string {
+	return splitStrings(s, n, re.split(stringToBytesUnsafe(s), n, splitKeep))
+}
+
+// SplitKeepBytes is like [PCREgexp.SplitKeep] but slices b.
+func (re *PCREgexp) SplitKeepBytes(b []byte, n int) [][]byte {
+	return splitBytes(b, n, re.split(b, n, splitKeep))
+}
+
+// SplitGroups is like [PCREgexp.Split], but the text of the subexpressions of
+// each match separating two substrings is put in between them, as with
+// Python's re.split. Subexpressions that did not participate in the match
+// yield empty strings. Without subexpressions, it is the same as Split; n
+// counts the substrings only.
+func (re *PCREgexp) SplitGroups(s string, n int) []string {
+	return splitStrings(s, n, re.split(stringToBytesUnsafe(s), n, splitGroups))
+}
+
+// SplitGroupsBytes is like [PCREgexp.SplitGroups] but slices b.
+// Subexpressions that did not participate in a match yield nil.
+func (re *PCREgexp) SplitGroupsBytes(b []byte, n int) [][]byte {
+	return splitBytes(b, n, re.split(b, n, splitGroups))
+}
@@ -0,0 +1,86 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestSplit_Stdlib(t *testing.T) {
+	patterns := []string{`,`, `\s*;\s*`, `x*`, ``, `a`, `$`, `^`}
+	subjects := []string{"", "a,b,,c,", " ; a;b ; ", "axxbxc", "banana", "abc", "héllo"}
+
+	for _, pattern := range patterns {
+		re := pcregexp.MustCompile(pattern)
+		std := regexp.MustCompile(pattern)
+
+		for _, s := range subjects {
+			for _, n := range []int{-1, 0, 1, 2, 3} {
+				want := std.Split(s, n)
+
+				if got := re.Split(s, n); !reflect.DeepEqual(got, want) {
+					t.Errorf("%q: Split(%q, %d) = %q, want %q", pattern, s, n, got, want)
+				}
+
+				var got []string
+				for _, part := range re.SplitBytes([]byte(s), n) {
+					got = append(got, string(part))
+				}
+				if !reflect.DeepEqual(got, want) && !(len(got) == 0 && len(want) == 0) {
+					t.Errorf("%q: SplitBytes(%q, %d) = %q, want %q", pattern, s, n, got, want)
+				}
+
+				if n < 0 && len(s) > 0 {
+					if got := strings.Join(re.SplitAfter(s, n), ""); got != s {
+						t.Errorf("%q: SplitAfter(%q) joins to %q", pattern, s, got)
+					}
+				}
+			}
+		}
+
+		re.Close()
+	}
+}
+
+func TestSplitVariants(t *testing.T) {
+	re := pcregexp.MustCompile(`\s*([,;])\s*|(\|)`)
+	defer re.Close()
+
+	s := "a , b;c|d"
+
+	tests := []struct {
+		name string
+		fn   func(string, int) []string
+		n    int
+		want []string
+	}{
+		{"SplitAfter", re.SplitAfter, -1, []string{"a , ", "b;", "c|", "d"}},
+		{"SplitAfter/n", re.SplitAfter, 2, []string{"a , ", "b;c|d"}},
+		{"SplitKeep", re.SplitKeep, -1, []string{"a", " , ", "b", ";", "c", "|", "d"}},
+		{"SplitKeep/n", re.SplitKeep, 2, []string{"a", " , ", "b;c|d"}},
+		{"SplitGroups", re.SplitGroups, -1, []string{"a", ",", "", "b", ";", "", "c", "", "|", "d"}},
+		{"SplitGroups/n", re.SplitGroups, 1, []string{"a , b;c|d"}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn(s, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s(%q, %d) = %q, want %q", tt.name, s, tt.n, got, tt.want)
+		}
+	}
+
+	// No separator is kept after an empty match at the end.
+	empty := pcregexp.MustCompile(`(x*)`)
+	defer empty.Close()
+
+	if got, want := empty.SplitGroups("ab", -1), []string{"a", "", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitGroups() = %q, want %q", got, want)
+	}
+
+	groups := re.SplitGroupsBytes([]byte(s), -1)
+	if groups[2] != nil || string(groups[1]) != "," {
+		t.Errorf("SplitGroupsBytes() = %q, want nil for the unset group", groups)
+	}
+}
@@ -0,0 +1,200 @@
+package pcregexp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Template is a replacement template parsed once by
+// [PCREgexp.CompileTemplate], so that it can be applied to many matches
+// without being parsed again. It refers to the subexpressions of the regexp
+// it was compiled for, and can only be used with that regexp or another
+// compiled from the same pattern with the same options.
+type Template struct {
+	pattern      string // pattern of the regexp the template was compiled for
+	opts         Option // and its options
+	source       string
+	parts        []templatePart
+	preserveCase bool
+}
+
+// templatePart is a piece of a template: literal text, or a reference to a
+// subexpression if group >= 0.
+type templatePart struct {
+	literal string
+	group   int
+}
+
+// PreserveCase returns a copy of t whose expansion takes the letter case of
+// the whole match, as the replacement of [PCREgexp.ReplaceAllPreserveCase]
+// does.
+func (t *Template) PreserveCase() *Template {
+	c := *t
+	c.preserveCase = true
+
+	return &c
+}
+
+// String returns the source text of the template.
+func (t *Template) String() string {
+	return t.source
+}
+
+// CompileTemplate parses tmpl into a [Template] for the matches of the regexp.
+// As in the templates of [regexp.Regexp.Expand], $name and ${name} stand for
+// the text of the subexpression with the given name or number, and $$ for a
+// literal $; a name is the longest sequence of letters, digits and
+// underscores. A $ followed by anything else is kept as is.
+//
+// Unlike in package regexp, a reference to a subexpression the regexp does
+// not have is an error, as is an unterminated ${.
+func (re *PCREgexp) CompileTemplate(tmpl string) (*Template, error) {
+	t := &Template{pattern: re.pattern, opts: re.opts, source: tmpl}
+	numSubexp := re.NumSubexp()
+
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, templatePart{literal: literal.String(), group: -1})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(tmpl); {
+		j := strings.IndexByte(tmpl[i:], '$')
+		if j < 0 {
+			literal.WriteString(tmpl[i:])
+			break
+		}
+
+		literal.WriteString(tmpl[i : i+j])
+		i += j + 1
+
+		var name string
+		switch {
+		case i < len(tmpl) && tmpl[i] == '$':
+			literal.WriteByte('$')
+			i++
+			continue
+		case i < len(tmpl) && tmpl[i] == '{':
+			end := strings.IndexByte(tmpl[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("template %q: unterminated ${ at offset %d", tmpl, i-1)
+			}
+
+			name, i = tmpl[i+1:i+end], i+end+1
+			if name == "" || templateName(name) != len(name) {
+				return nil, fmt.Errorf("template %q: invalid name %q", tmpl, name)
+			}
+		default:
+			n := templateName(tmpl[i:])
+			if n == 0 {
+				literal.WriteByte('$')
+				continue
+			}
+
+			name, i = tmpl[i:i+n], i+n
+		}
+
+		group, err := strconv.Atoi(name)
+		if err != nil {
+			group = re.SubexpIndex(name)
+		}
+
+		if group < 0 || group > numSubexp {
+			return nil, fmt.Errorf("template %q: no subexpression %q", tmpl, name)
+		}
+
+		flush()
+		t.parts = append(t.parts, templatePart{group: group})
+	}
+	flush()
+
+	return t, nil
+}
+
+// templateName returns the length of the name at the start of s.
+func templateName(s string) int {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '_' && !isAlnum(c) {
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+// checkTemplate panics if t was compiled for a regexp whose subexpressions
+// may differ from those of re.
+func (re *PCREgexp) checkTemplate(t *Template) {
+	if t.pattern != re.pattern || t.opts != re.opts {
+		panic(fmt.Sprintf("pcregexp: template %q compiled for %q used with %q", t.source, t.pattern, re.pattern))
+	}
+}
+
+// ExpandTemplate appends t to dst, with the references to subexpressions
+// replaced by their text in src, at the index pairs of match as returned by
+// [PCREgexp.FindSubmatchIndex]. Subexpressions that did not participate in the
+// match expand to nothing. A template returned by [Template.PreserveCase]
+// expands in the letter case of the match.
+//
+// ExpandTemplate panics if t was compiled for a regexp with another pattern
+// or other options.
+func (re *PCREgexp) ExpandTemplate(dst []byte, t *Template, src []byte, match []int) []byte {
+	re.checkTemplate(t)
+
+	dst, _ = expand(dst, nil, t, src, match)
+
+	return dst
+}
+
+// expand is ExpandTemplate, expanding a template that preserves case into
+// scratch first. It returns scratch for reuse by the next expansion.
+func expand(dst, scratch []byte, t *Template, src []byte, match []int) ([]byte, []byte) {
+	if t.preserveCase && len(match) >= 2 && match[0] >= 0 {
+		scratch = expandTemplate(scratch[:0], t, src, match)
+		return appendCase(dst, scratch, src[match[0]:match[1]]), scratch
+	}
+
+	return expandTemplate(dst, t, src, match), scratch
+}
+
+// expandTemplate is ExpandTemplate, leaving the case of t as is.
+func expandTemplate(dst []byte, t *Template, src []byte, match []int) []byte {
+	for _, part := range t.parts {
+		if part.group < 0 {
+			dst = append(dst, part.literal...)
+		} else if g := 2 * part.group; g+1 < len(match) && match[g] >= 0 {
+			dst = append(dst, src[match[g]:match[g+1]]...)
+		}
+	}
+
+	return dst
+}
+
+// ExpandTemplateString is like [PCREgexp.ExpandTemplate] but src is a string.
+func (re *PCREgexp) ExpandTemplateString(dst []byte, t *Template, src string, match []int) []byte {
+	return re.ExpandTemplate(dst, t, stringToBytesUnsafe(src), match)
+}
+
+// ReplaceAllTemplate returns a copy of src in which all matches of the regexp
+// have been replaced by t, expanded as by [PCREgexp.ExpandTemplate]. Like
+// ExpandTemplate, it panics if t was compiled for a regexp with another
+// pattern or other options.
+func (re *PCREgexp) ReplaceAllTemplate(src []byte, t *Template) []byte {
+	re.checkTemplate(t)
+
+	var scratch []byte
+
+	return re.replaceAll(make([]byte, 0, len(src)), src, func(dst []byte, match []int) []byte {
+		dst, scratch = expand(dst, scratch, t, src, match)
+		return dst
+	})
+}
+
+// ReplaceAllTemplateString is like [PCREgexp.ReplaceAllTemplate] but works on
+// strings.
+func (re *PCREgexp) ReplaceAllTemplateString(src string, t *Template) string {
+	return bytesToStringUnsafe(re.ReplaceAllTemplate(stringToBytesUnsafe(src), t))
+}
@@ -0,0 +1,88 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestSubexpNames(t *testing.T) {
+	re := pcregexp.MustCompile(`(?<year>\d{4})-(\d\d)-(?<day>\d\d)`)
+	defer re.Close()
+
+	if got, want := re.SubexpNames(), []string{"", "year", "", "day"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SubexpNames() = %q, want %q", got, want)
+	}
+
+	if got := re.SubexpIndex("day"); got != 3 {
+		t.Errorf("SubexpIndex(day) = %d, want 3", got)
+	}
+
+	if got := re.SubexpIndex("month"); got != -1 {
+		t.Errorf("SubexpIndex(month) = %d, want -1", got)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	re := pcregexp.MustCompile(`(?<year>\d{4})-(\d\d)-(?<day>\d\d)(Z)?`)
+	defer re.Close()
+
+	tmpl, err := re.CompileTemplate(`${day}/$2/$year$4 ($$1, $ and $0)`)
+	if err != nil {
+		t.Fatalf("CompileTemplate() error = %v", err)
+	}
+
+	src := "from 2024-01-02 to 2025-12-31Z"
+
+	got := re.ReplaceAllTemplateString(src, tmpl)
+	if want := "from 02/01/2024 ($1, $ and 2024-01-02) to 31/12/2025Z ($1, $ and 2025-12-31Z)"; got != want {
+		t.Errorf("ReplaceAllTemplateString() = %q, want %q", got, want)
+	}
+
+	match := re.FindStringSubmatchIndex(src)
+	if got, want := string(re.ExpandTemplateString([]byte(">"), tmpl, src, match)), ">02/01/2024 ($1, $ and 2024-01-02)"; got != want {
+		t.Errorf("ExpandTemplateString() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{`${month}`, `$5`, `$1x`, `${day`, `${}`} {
+		if _, err := re.CompileTemplate(bad); err == nil {
+			t.Errorf("CompileTemplate(%q) error = nil, want an error", bad)
+		}
+	}
+}
+
+func TestTemplate_OtherRegexp(t *testing.T) {
+	re := pcregexp.MustCompile(`(\w+)@(\w+)`)
+	defer re.Close()
+
+	tmpl, err := re.CompileTemplate(`$2`)
+	if err != nil {
+		t.Fatalf("CompileTemplate() error = %v", err)
+	}
+
+	// The same pattern compiled again has the same subexpressions.
+	same := pcregexp.MustCompile(`(\w+)@(\w+)`)
+	defer same.Close()
+
+	if got, want := same.ReplaceAllTemplateString("me@host", tmpl), "host"; got != want {
+		t.Errorf("ReplaceAllTemplateString() = %q, want %q", got, want)
+	}
+
+	other := pcregexp.MustCompile(`(\w+)`)
+	defer other.Close()
+
+	for name, fn := range map[string]func(){
+		"ReplaceAllTemplateString": func() { other.ReplaceAllTemplateString("no match here!", tmpl) },
+		"ExpandTemplate":           func() { other.ExpandTemplate(nil, tmpl, []byte("me"), []int{0, 2, 0, 2}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s() with a template of another regexp did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
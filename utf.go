@@ -0,0 +1,71 @@
+package pcregexp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBadUTF is matched by the errors of [PCREgexp.Validate] for subjects that
+// are not valid UTF-8.
+var ErrBadUTF = errors.New("invalid UTF-8 subject")
+
+// UTFError reports where a subject fails the UTF-8 check of PCRE2.
+type UTFError struct {
+	// Offset is the offset of the first byte of the invalid sequence.
+	Offset int
+
+	// Code is the PCRE2 error code telling what is wrong with it, from
+	// PCRE2_ERROR_UTF8_ERR1 (-3) to PCRE2_ERROR_UTF8_ERR21 (-23).
+	Code int
+}
+
+func (e *UTFError) Error() string {
+	return fmt.Sprintf("%v at offset %d, error code %d", ErrBadUTF, e.Offset, e.Code)
+}
+
+// Is makes errors.Is(err, [ErrBadUTF]) report true for a *UTFError.
+func (e *UTFError) Is(target error) bool {
+	return target == ErrBadUTF
+}
+
+// Validate checks b as PCRE2 does before matching it in strict UTF mode, that
+// is with the [UTF] option but without [MatchInvalidUTF]. It returns a
+// *[UTFError] if b is not valid UTF-8, which is why the regexp never matches
+// it, and nil otherwise or in the other modes.
+//
+// Validate runs a match of b, calling the callout function if any.
+func (re *PCREgexp) Validate(b []byte) error {
+	if re.code == 0 || !re.opts.strictUTF() {
+		return nil
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return nil
+	}
+	defer re.putMatchData(m)
+
+	mcontext, ok := m.context.get(re.mem.gctx, -1)
+	if !ok {
+		return nil
+	}
+
+	return utfError(m, int32(re.native(m, b, 0, 0, mcontext)))
+}
+
+// utfError returns the *UTFError of the match in m that failed with rc, or
+// nil if rc is not a UTF-8 error code.
+func utfError(m *matchData, rc int32) error {
+	if rc > pcre2ErrorUTF8Err1 || rc < pcre2ErrorUTF8Err21 {
+		return nil
+	}
+
+	// On a UTF-8 error, the start character is where the invalid sequence
+	// begins.
+	return &UTFError{Offset: int(pcre2_get_startchar(m.handle)), Code: int(rc)}
+}
+
+// ValidateString is like [PCREgexp.Validate] but checks s.
+func (re *PCREgexp) ValidateString(s string) error {
+	return re.Validate(stringToBytesUnsafe(s))
+}
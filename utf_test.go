@@ -0,0 +1,98 @@
+package pcregexp_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func TestUTFModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		opts    pcregexp.Option
+		subject string
+		want    [][]int
+	}{
+		{"bytes", `\xff`, 0, "a\xffb", [][]int{{1, 2}}},
+		{"bytes empty matches", `x*`, 0, "é\xff", [][]int{{0, 0}, {2, 2}, {3, 3}}},
+		{"raw bytes empty matches", `x*`, pcregexp.NeverUTF, "é", [][]int{{0, 0}, {1, 1}, {2, 2}}},
+		{"UTF empty matches", `x*`, pcregexp.UTF, "é", [][]int{{0, 0}, {2, 2}}},
+		{"UTF invalid subject", `\w+`, pcregexp.UTF, "ab\xffcd", nil},
+		{"invalid UTF", `\w+`, pcregexp.MatchInvalidUTF, "ab\xffcd", [][]int{{0, 2}, {3, 5}}},
+		{"invalid UTF empty matches", `x*`, pcregexp.MatchInvalidUTF, "a\xffé", [][]int{{0, 0}, {1, 1}, {2, 2}, {4, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := pcregexp.MustCompileWithOptions(tt.pattern, tt.opts)
+			defer re.Close()
+
+			if got := re.FindAllStringIndex(tt.subject, -1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAllStringIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	re := pcregexp.MustCompileWithOptions(`c`, pcregexp.UTF)
+	defer re.Close()
+
+	err := re.ValidateString("ab\xffc")
+
+	var utfErr *pcregexp.UTFError
+	if !errors.As(err, &utfErr) || !errors.Is(err, pcregexp.ErrBadUTF) {
+		t.Fatalf("ValidateString() = %v, want a *UTFError", err)
+	}
+
+	if utfErr.Offset != 2 {
+		t.Errorf("Offset = %d, want 2", utfErr.Offset)
+	}
+
+	if err := re.ValidateString("abc"); err != nil {
+		t.Errorf("ValidateString() = %v, want nil", err)
+	}
+
+	lenient := pcregexp.MustCompileWithOptions(`c`, pcregexp.MatchInvalidUTF)
+	defer lenient.Close()
+
+	if err := lenient.ValidateString("ab\xffc"); err != nil {
+		t.Errorf("ValidateString() = %v, want nil with MatchInvalidUTF", err)
+	}
+
+	if !lenient.MatchString("ab\xffc") {
+		t.Error("MatchString() = false, want true with MatchInvalidUTF")
+	}
+}
+
+func TestMatchErr_UTF(t *testing.T) {
+	// `foo\d` has a prefilter rejecting the subject without calling PCRE2.
+	for _, pattern := range []string{`\w\d`, `foo\d`} {
+		re := pcregexp.MustCompileWithOptions(pattern, pcregexp.UTF)
+
+		ok, err := re.MatchStringErr("bar\xffbaz")
+
+		var utfErr *pcregexp.UTFError
+		if ok || !errors.As(err, &utfErr) || !errors.Is(err, pcregexp.ErrBadUTF) {
+			t.Errorf("%q: MatchStringErr() = %v, %v, want false, a *UTFError", pattern, ok, err)
+		} else if utfErr.Offset != 3 {
+			t.Errorf("%q: Offset = %d, want 3", pattern, utfErr.Offset)
+		}
+
+		if ok, err := re.MatchStringErr("bar baz"); ok || err != nil {
+			t.Errorf("%q: MatchStringErr() = %v, %v, want false, nil", pattern, ok, err)
+		}
+
+		re.Close()
+	}
+
+	lenient := pcregexp.MustCompileWithOptions(`foo\d`, pcregexp.MatchInvalidUTF)
+	defer lenient.Close()
+
+	if ok, err := lenient.MatchStringErr("bar\xfffoo1"); !ok || err != nil {
+		t.Errorf("MatchStringErr() = %v, %v, want true, nil with MatchInvalidUTF", ok, err)
+	}
+}
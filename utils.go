@@ -1,13 +1,69 @@
 package pcregexp
 
-import "unsafe"
+import (
+	"sync"
+	"unsafe"
+)
 
 // stringToBytesUnsafe returns a byte slice header that points to the string's
 // data. This conversion is safe only if the receiver does not modify the
 // returned slice.
 func stringToBytesUnsafe(s string) []byte {
-	return *(*[]byte)(unsafe.Pointer(&s))
+	if len(s) == 0 {
+		return nil
+	}
+
+	// The data pointer is the first word of a string header.
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&s)), len(s))
 }
 
 // ptr aliases [unsafe.Pointer].
 type ptr = unsafe.Pointer
+
+// handles maps the opaque values handed to PCRE2 as user data (e.g. the
+// callout_data of a match context) back to the Go values they stand for.
+//
+// Go pointers must not be retained by C code, so callbacks receive a small
+// integer handle instead and resolve it here.
+var handles struct {
+	sync.RWMutex
+	next uintptr
+	m    map[uintptr]any
+}
+
+// newHandle registers v and returns a non-zero handle for it.
+func newHandle(v any) uintptr {
+	handles.Lock()
+	defer handles.Unlock()
+
+	if handles.m == nil {
+		handles.m = make(map[uintptr]any)
+	}
+
+	handles.next++
+	handles.m[handles.next] = v
+
+	return handles.next
+}
+
+// loadHandle returns the value registered for h, or nil.
+func loadHandle(h uintptr) any {
+	handles.RLock()
+	defer handles.RUnlock()
+
+	return handles.m[h]
+}
+
+// deleteHandle releases h.
+func deleteHandle(h uintptr) {
+	handles.Lock()
+	defer handles.Unlock()
+
+	delete(handles.m, h)
+}
+
+// bytesToStringUnsafe returns a string that shares the data of b. This
+// conversion is safe only if b is not modified afterwards.
+func bytesToStringUnsafe(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
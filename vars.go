@@ -12,13 +12,27 @@ var (
 
 	// pcre2_pattern_info_8: int pcre2_pattern_info_8(const pcre2_code *code,
 	//    uint32_t what, void *where);
-	pcre2_pattern_info func(code uintptr, what uint32, where uintptr) int32
+	pcre2_pattern_info func(code uintptr, what uint32, where ptr) int32
 
 	// pcre2_match_8: int pcre2_match_8(const pcre2_code *code,
 	//    PCRE2_SPTR subject, PCRE2_SIZE length, PCRE2_SIZE startoffset,
 	//	  uint32_t options, pcre2_match_data *match_data,
 	// 	  pcre2_match_context *mcontext);
-	pcre2_match func(code uintptr, subject *uint8, length uint64, startoffset uint64, options uint32, matchData uintptr, matchContext uintptr) int32
+	//
+	// This is the address of the symbol: pcre2_match is called through
+	// nativeCall, as registered functions allocate on every call.
+	pcre2_match uintptr
+
+	// pcre2_dfa_match_8: int pcre2_dfa_match_8(const pcre2_code *code,
+	//    PCRE2_SPTR subject, PCRE2_SIZE length, PCRE2_SIZE startoffset,
+	//    uint32_t options, pcre2_match_data *match_data,
+	//    pcre2_match_context *mcontext, int *workspace, PCRE2_SIZE wscount);
+	pcre2_dfa_match func(code uintptr, subject *uint8, length uint64, startOffset uint64, options uint32, matchData uintptr, matchContext uintptr, workspace *int32, wscount uint64) int32
+
+	// pcre2_match_data_create_8:
+	// 	  pcre2_match_data *pcre2_match_data_create_8(uint32_t ovecsize,
+	// 	  	  pcre2_general_context *gcontext);
+	pcre2_match_data_create func(ovecsize uint32, generalContext uintptr) uintptr
 
 	// pcre2_match_data_create_from_pattern_8:
 	// 	  pcre2_match_data *pcre2_match_data_create_from_pattern_8(
@@ -32,4 +46,79 @@ var (
 	// pcre2_get_ovector_pointer_8:
 	// 	  PCRE2_SIZE *pcre2_get_ovector_pointer_8(pcre2_match_data *match_data);
 	pcre2_get_ovector_pointer func(matchData uintptr) *uint64
+
+	// pcre2_get_ovector_count_8:
+	// 	  uint32_t pcre2_get_ovector_count_8(pcre2_match_data *match_data);
+	pcre2_get_ovector_count func(matchData uintptr) uint32
+
+	// pcre2_get_mark_8:
+	// 	  PCRE2_SPTR pcre2_get_mark_8(pcre2_match_data *match_data);
+	pcre2_get_mark func(matchData uintptr) *uint8
+
+	// pcre2_get_startchar_8:
+	// 	  PCRE2_SIZE pcre2_get_startchar_8(pcre2_match_data *match_data);
+	pcre2_get_startchar func(matchData uintptr) uint64
+
+	// pcre2_match_context_create_8:
+	// 	  pcre2_match_context *pcre2_match_context_create_8(
+	// 	  	  pcre2_general_context *gcontext);
+	pcre2_match_context_create func(generalContext uintptr) uintptr
+
+	// pcre2_match_context_free_8:
+	// 	  void pcre2_match_context_free_8(pcre2_match_context *mcontext);
+	pcre2_match_context_free func(matchContext uintptr)
+
+	// pcre2_set_callout_8:
+	// 	  int pcre2_set_callout_8(pcre2_match_context *mcontext,
+	// 	  	  int (*callout_function)(pcre2_callout_block *, void *),
+	// 	  	  void *callout_data);
+	pcre2_set_callout func(matchContext uintptr, callout uintptr, calloutData uintptr) int32
+
+	// pcre2_set_offset_limit_8:
+	// 	  int pcre2_set_offset_limit_8(pcre2_match_context *mcontext,
+	// 	  	  PCRE2_SIZE value);
+	pcre2_set_offset_limit func(matchContext uintptr, value uint64) int32
+
+	// pcre2_callout_enumerate_8:
+	// 	  int pcre2_callout_enumerate_8(const pcre2_code *code,
+	// 	  	  int (*callback)(pcre2_callout_enumerate_block *, void *),
+	// 	  	  void *user_data);
+	pcre2_callout_enumerate func(code uintptr, callback uintptr, userData uintptr) int32
+
+	// pcre2_config_8: int pcre2_config_8(uint32_t what, void *where);
+	pcre2_config func(what uint32, where ptr) int32
+
+	// pcre2_serialize_encode_8:
+	// 	  int32_t pcre2_serialize_encode_8(const pcre2_code **codes,
+	// 	  	  int32_t number_of_codes, uint8_t **serialized_bytes,
+	// 	  	  PCRE2_SIZE *serialized_size, pcre2_general_context *gcontext);
+	pcre2_serialize_encode func(codes *uintptr, numberOfCodes int32, serializedBytes **uint8, serializedSize *uint64, generalContext uintptr) int32
+
+	// pcre2_serialize_decode_8:
+	// 	  int32_t pcre2_serialize_decode_8(pcre2_code **codes,
+	// 	  	  int32_t number_of_codes, const uint8_t *bytes,
+	// 	  	  pcre2_general_context *gcontext);
+	pcre2_serialize_decode func(codes *uintptr, numberOfCodes int32, bytes *uint8, generalContext uintptr) int32
+
+	// pcre2_serialize_free_8: void pcre2_serialize_free_8(uint8_t *bytes);
+	pcre2_serialize_free func(bytes *uint8)
+
+	// pcre2_general_context_create_8:
+	// 	  pcre2_general_context *pcre2_general_context_create_8(
+	// 	  	  void *(*private_malloc)(PCRE2_SIZE, void *),
+	// 	  	  void (*private_free)(void *, void *), void *memory_data);
+	pcre2_general_context_create func(privateMalloc uintptr, privateFree uintptr, memoryData uintptr) uintptr
+
+	// pcre2_general_context_free_8:
+	// 	  void pcre2_general_context_free_8(pcre2_general_context *gcontext);
+	pcre2_general_context_free func(generalContext uintptr)
+
+	// pcre2_compile_context_create_8:
+	// 	  pcre2_compile_context *pcre2_compile_context_create_8(
+	// 	  	  pcre2_general_context *gcontext);
+	pcre2_compile_context_create func(generalContext uintptr) uintptr
+
+	// pcre2_compile_context_free_8:
+	// 	  void pcre2_compile_context_free_8(pcre2_compile_context *ccontext);
+	pcre2_compile_context_free func(compileContext uintptr)
 )
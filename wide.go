@@ -0,0 +1,557 @@
+package pcregexp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// CodeUnit is the code unit of the subjects of a [WideRegexp]: uint16 for
+// UTF-16 and rune for UTF-32.
+type CodeUnit interface {
+	uint16 | rune
+}
+
+// WideRegexp is a regular expression matching UTF-16 or UTF-32 subjects,
+// compiled by the 16-bit or 32-bit PCRE2 library. It offers the Find, Replace
+// and Split methods of [PCREgexp] over slices of code units, and all offsets
+// it returns are in code units.
+//
+// The libraries are loaded on the first call to [Compile16] or [Compile32],
+// so that programs matching only UTF-8 text do not need them. Native memory
+// allocated for wide regexps is not subject to [SetMemoryLimit].
+type WideRegexp[T CodeUnit] struct {
+	pattern   string       // original pattern
+	opts      Option       // compile options
+	lib       *wideLib     // library the pattern was compiled by
+	code      uintptr      // pointer to compiled pcre2_code
+	mu        sync.Mutex   // guards matchData
+	matchData []*matchData // idle match data, reused across matches
+}
+
+// Regexp16 is a regular expression matching UTF-16 subjects.
+type Regexp16 = WideRegexp[uint16]
+
+// Regexp32 is a regular expression matching UTF-32 subjects.
+type Regexp32 = WideRegexp[rune]
+
+// wideLib holds the functions of the 16-bit or 32-bit PCRE2 library, which
+// have the signatures of their 8-bit counterparts in vars.go with code units
+// of another width.
+type wideLib struct {
+	compile                    func(pattern ptr, length uint64, options uint32, errorcode *int32, erroroffset *uint64, compileContext uintptr) uintptr
+	codeFree                   func(code uintptr)
+	patternInfo                func(code uintptr, what uint32, where ptr) int32
+	match                      uintptr
+	matchDataCreateFromPattern func(code uintptr, generalContext uintptr) uintptr
+	matchDataFree              func(matchData uintptr)
+	getOvectorPointer          func(matchData uintptr) *uint64
+	getOvectorCount            func(matchData uintptr) uint32
+}
+
+// wideLibs holds the 16-bit and 32-bit libraries, loaded on demand.
+var wideLibs [2]struct {
+	once sync.Once
+	lib  *wideLib
+	err  error
+}
+
+// loadWideLib loads the PCRE2 library for code units of the given width in
+// bits, 16 or 32, once.
+func loadWideLib(width int) (*wideLib, error) {
+	l := &wideLibs[width/16-1]
+	l.once.Do(func() {
+		l.lib, l.err = openWideLib(width)
+	})
+
+	return l.lib, l.err
+}
+
+func openWideLib(width int) (*wideLib, error) {
+	libPath, err := libraryName(width)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := openLibrary(libPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", libPath, err)
+	}
+
+	lib := new(wideLib)
+	funcs := [][2]any{
+		{&lib.compile, "pcre2_compile"},
+		{&lib.codeFree, "pcre2_code_free"},
+		{&lib.patternInfo, "pcre2_pattern_info"},
+		{&lib.matchDataCreateFromPattern, "pcre2_match_data_create_from_pattern"},
+		{&lib.matchDataFree, "pcre2_match_data_free"},
+		{&lib.getOvectorPointer, "pcre2_get_ovector_pointer"},
+		{&lib.getOvectorCount, "pcre2_get_ovector_count"},
+	}
+
+	for _, f := range funcs {
+		name := fmt.Sprintf("%s_%d", f[1], width)
+
+		sym, err := lookupSymbol(handle, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", name, err)
+		}
+
+		purego.RegisterFunc(f[0], sym)
+	}
+
+	name := fmt.Sprintf("pcre2_match_%d", width)
+	if lib.match, err = lookupSymbol(handle, name); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", name, err)
+	}
+
+	return lib, nil
+}
+
+// Compile16 compiles pattern with the given options for matching UTF-16
+// subjects. Without the [UTF] option, each code unit is a character of its
+// own, and surrogate pairs are not combined.
+func Compile16(pattern string, opts Option) (*Regexp16, error) {
+	return compileWide[uint16](pattern, opts)
+}
+
+// Compile32 compiles pattern with the given options for matching UTF-32
+// subjects.
+func Compile32(pattern string, opts Option) (*Regexp32, error) {
+	return compileWide[rune](pattern, opts)
+}
+
+// MustCompile16 is like [Compile16] but panics on error.
+func MustCompile16(pattern string, opts Option) *Regexp16 {
+	re, err := Compile16(pattern, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	return re
+}
+
+// MustCompile32 is like [Compile32] but panics on error.
+func MustCompile32(pattern string, opts Option) *Regexp32 {
+	re, err := Compile32(pattern, opts)
+	if err != nil {
+		panic(err)
+	}
+
+	return re
+}
+
+func compileWide[T CodeUnit](pattern string, opts Option) (*WideRegexp[T], error) {
+	lib, err := loadWideLib(unitWidth[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	var errcode int32
+	var errOffset uint64
+
+	units := encodeUnits[T](pattern)
+	patPtr := ptr(&emptySubject)
+	if len(units) > 0 {
+		patPtr = ptr(&units[0])
+	}
+
+	code := lib.compile(patPtr, uint64(len(units)), uint32(opts), &errcode, &errOffset, 0)
+	if code == 0 {
+		if errcode == pcre2ErrorHeapFailed {
+			// The memory of wide regexps is not subject to the limit.
+			return nil, errors.New("pcre2_compile failed: out of memory")
+		}
+
+		return nil, fmt.Errorf("pcre2_compile failed at offset %d, error code %d", errOffset, errcode)
+	}
+
+	return &WideRegexp[T]{pattern: pattern, opts: opts, lib: lib, code: code}, nil
+}
+
+// unitWidth returns the width of T in bits.
+func unitWidth[T CodeUnit]() int {
+	var u T
+	return int(unsafe.Sizeof(u)) * 8
+}
+
+// encodeUnits encodes s in UTF-16 or UTF-32, depending on T.
+func encodeUnits[T CodeUnit](s string) []T {
+	runes := []rune(s)
+
+	var units []T
+	if unitWidth[T]() == 16 {
+		for _, u := range utf16.Encode(runes) {
+			units = append(units, T(u))
+		}
+
+		return units
+	}
+
+	units = make([]T, len(runes))
+	for i, r := range runes {
+		units[i] = T(r)
+	}
+
+	return units
+}
+
+// Close releases the compiled pattern and its match data. The regexp must not
+// be used afterwards.
+func (re *WideRegexp[T]) Close() {
+	re.mu.Lock()
+	for _, m := range re.matchData {
+		re.lib.matchDataFree(m.handle)
+	}
+	re.matchData = nil
+	re.mu.Unlock()
+
+	if re.code != 0 {
+		re.lib.codeFree(re.code)
+		re.code = 0
+	}
+}
+
+// getMatchData returns an idle match data block, creating one if all are in
+// use.
+func (re *WideRegexp[T]) getMatchData() *matchData {
+	re.mu.Lock()
+	if n := len(re.matchData); n > 0 {
+		m := re.matchData[n-1]
+		re.matchData = re.matchData[:n-1]
+		re.mu.Unlock()
+
+		return m
+	}
+	re.mu.Unlock()
+
+	md := re.lib.matchDataCreateFromPattern(re.code, 0)
+	if md == 0 {
+		return nil
+	}
+
+	n := re.lib.getOvectorCount(md)
+
+	return &matchData{
+		handle:  md,
+		ovector: unsafe.Slice((*int)(ptr(re.lib.getOvectorPointer(md))), 2*n),
+	}
+}
+
+// putMatchData hands m back for reuse by getMatchData.
+func (re *WideRegexp[T]) putMatchData(m *matchData) {
+	re.mu.Lock()
+	re.matchData = append(re.matchData, m)
+	re.mu.Unlock()
+}
+
+// exec runs pcre2_match on subject from code unit offset start. It returns
+// the number of offset pairs set in m.ovector, or a negative PCRE2 error code.
+func (re *WideRegexp[T]) exec(m *matchData, subject []T, start int, options uint32) int {
+	m.subject = ptr(&emptySubject)
+	if len(subject) > 0 {
+		m.subject = ptr(&subject[0])
+	}
+
+	rc := m.call.call7(re.lib.match, re.code, uintptr(m.subject), uintptr(len(subject)),
+		uintptr(start), uintptr(options), m.handle, 0)
+	m.subject = nil
+
+	return int(int32(rc))
+}
+
+// doMatch appends to dst the offsets of the first pairs offset pairs (all of
+// them if pairs < 0) of the leftmost match in b, and reports whether there was
+// a match.
+func (re *WideRegexp[T]) doMatch(dst []int, b []T, pairs int) ([]int, bool) {
+	if re.code == 0 {
+		return dst, false
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return dst, false
+	}
+	defer re.putMatchData(m)
+
+	if re.exec(m, b, 0, 0) < 0 {
+		return dst, false
+	}
+
+	return append(dst, m.pairs(pairs)...), true
+}
+
+// allMatches calls deliver with the offsets of up to n successive,
+// non-overlapping matches in b, or all of them if n < 0, like
+// [PCREgexp.allMatches]. After an empty match, the search resumes one
+// character later, stepping over both halves of a UTF-16 surrogate pair in
+// UTF mode.
+func (re *WideRegexp[T]) allMatches(b []T, n int, deliver func(match []int)) {
+	if re.code == 0 || n == 0 {
+		return
+	}
+
+	m := re.getMatchData()
+	if m == nil {
+		return
+	}
+	defer re.putMatchData(m)
+
+	var options uint32
+
+	for pos, i, prevEnd := 0, 0, -1; (n < 0 || i < n) && pos <= len(b); {
+		if re.exec(m, b, pos, options) < 0 {
+			break
+		}
+
+		if re.opts.strictUTF() {
+			options = pcre2NoUTFCheck
+		}
+
+		match := m.ovector
+		accept := accepts(pos, prevEnd, match)
+		pos, prevEnd = nextUnitPos(b, pos, match, re.opts.utf()), match[1]
+
+		if accept {
+			deliver(match)
+			i++
+		}
+	}
+}
+
+// nextUnitPos is [PCREgexp.nextPos] for subjects of UTF-16 or UTF-32 code
+// units, which steps over a surrogate pair as a whole in UTF mode.
+func nextUnitPos[T CodeUnit](b []T, pos int, match []int, utf bool) int {
+	if match[1] > pos {
+		return match[1]
+	}
+
+	if pos >= len(b) {
+		return len(b) + 1
+	}
+
+	if utf && pos+1 < len(b) && unitWidth[T]() == 16 &&
+		utf16.IsSurrogate(rune(b[pos])) && b[pos] < 0xdc00 &&
+		utf16.IsSurrogate(rune(b[pos+1])) && b[pos+1] >= 0xdc00 {
+		return pos + 2
+	}
+
+	return pos + 1
+}
+
+// Match reports whether the regexp matches b.
+func (re *WideRegexp[T]) Match(b []T) bool {
+	_, ok := re.doMatch(nil, b, 0)
+	return ok
+}
+
+// Find returns a slice holding the text of the leftmost match in b, or nil if
+// there is none.
+func (re *WideRegexp[T]) Find(b []T) []T {
+	var buf [2]int
+
+	a, ok := re.doMatch(buf[:0], b, 1)
+	if !ok {
+		return nil
+	}
+
+	return b[a[0]:a[1]:a[1]]
+}
+
+// FindIndex returns a two-element slice of integers defining the location of
+// the leftmost match in b, in code units.
+func (re *WideRegexp[T]) FindIndex(b []T) []int {
+	a, ok := re.doMatch(nil, b, 1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindSubmatch returns a slice of slices holding the text of the leftmost
+// match and the matches of any subexpressions, which are nil if they did not
+// participate in the match.
+func (re *WideRegexp[T]) FindSubmatch(b []T) [][]T {
+	var buf [32]int
+
+	a, ok := re.doMatch(buf[:0], b, -1)
+	if !ok {
+		return nil
+	}
+
+	return unitSubmatches(b, a)
+}
+
+// FindSubmatchIndex returns a slice holding the index pairs identifying the
+// leftmost match and the matches of any subexpressions.
+func (re *WideRegexp[T]) FindSubmatchIndex(b []T) []int {
+	a, ok := re.doMatch(nil, b, -1)
+	if !ok {
+		return nil
+	}
+
+	return a
+}
+
+// FindAll returns a slice of up to n successive matches in b, or all of them
+// if n < 0.
+func (re *WideRegexp[T]) FindAll(b []T, n int) [][]T {
+	var matches [][]T
+
+	re.allMatches(b, n, func(match []int) {
+		matches = append(matches, b[match[0]:match[1]:match[1]])
+	})
+
+	return matches
+}
+
+// FindAllIndex returns a slice of index pairs identifying up to n successive
+// matches in b, or all of them if n < 0.
+func (re *WideRegexp[T]) FindAllIndex(b []T, n int) [][]int {
+	var offsets []int
+
+	re.allMatches(b, n, func(match []int) {
+		offsets = append(offsets, match[:2]...)
+	})
+
+	return chunk(offsets, 2)
+}
+
+// FindAllSubmatch is like [WideRegexp.FindSubmatch] but returns up to n
+// successive matches, or all of them if n < 0.
+func (re *WideRegexp[T]) FindAllSubmatch(b []T, n int) [][][]T {
+	var results [][][]T
+
+	re.allMatches(b, n, func(match []int) {
+		results = append(results, unitSubmatches(b, match))
+	})
+
+	return results
+}
+
+// FindAllSubmatchIndex is like [WideRegexp.FindSubmatchIndex] but returns up
+// to n successive matches, or all of them if n < 0.
+func (re *WideRegexp[T]) FindAllSubmatchIndex(b []T, n int) [][]int {
+	var offsets []int
+	stride := 0
+
+	re.allMatches(b, n, func(match []int) {
+		stride = len(match)
+		offsets = append(offsets, match...)
+	})
+
+	return chunk(offsets, stride)
+}
+
+// unitSubmatches returns the slices of b at the index pairs of a match, or nil
+// for subexpressions that did not participate in it.
+func unitSubmatches[T CodeUnit](b []T, a []int) [][]T {
+	matches := make([][]T, len(a)/2)
+	for i := range matches {
+		if a[2*i] >= 0 {
+			matches[i] = b[a[2*i]:a[2*i+1]:a[2*i+1]]
+		}
+	}
+
+	return matches
+}
+
+// ReplaceAll returns a copy of src, replacing matches of the regexp with repl.
+func (re *WideRegexp[T]) ReplaceAll(src, repl []T) []T {
+	return re.replaceAll(src, func(dst []T, _ []int) []T {
+		return append(dst, repl...)
+	})
+}
+
+// ReplaceAllFunc returns a copy of src in which all matches of the regexp
+// have been replaced by the return value of repl applied to the matched code
+// units.
+func (re *WideRegexp[T]) ReplaceAllFunc(src []T, repl func([]T) []T) []T {
+	return re.replaceAll(src, func(dst []T, match []int) []T {
+		return append(dst, repl(src[match[0]:match[1]:match[1]])...)
+	})
+}
+
+// replaceAll returns a copy of src, with every match of the regexp replaced by
+// what repl appends for it.
+func (re *WideRegexp[T]) replaceAll(src []T, repl func(dst []T, match []int) []T) []T {
+	dst := make([]T, 0, len(src))
+	last := 0
+
+	re.allMatches(src, -1, func(match []int) {
+		if match[0] > last {
+			dst = append(dst, src[last:match[0]]...)
+		}
+
+		dst = repl(dst, match)
+		if match[1] > last {
+			last = match[1]
+		}
+	})
+
+	return append(dst, src[last:]...)
+}
+
+// Split slices b into subslices separated by matches of the regexp, like
+// [PCREgexp.Split]. If n > 0, Split returns at most n subslices, otherwise it
+// returns all of them.
+func (re *WideRegexp[T]) Split(b []T, n int) [][]T {
+	if n == 0 {
+		return nil
+	}
+
+	if len(re.pattern) > 0 && len(b) == 0 {
+		return [][]T{{}}
+	}
+
+	matches := re.FindAllIndex(b, n)
+	parts := make([][]T, 0, len(matches)+1)
+
+	beg, end := 0, 0
+	for _, match := range matches {
+		if n > 0 && len(parts) == n-1 {
+			break
+		}
+
+		end = match[0]
+		if match[1] != 0 {
+			parts = append(parts, b[beg:end:end])
+		}
+		beg = match[1]
+	}
+
+	if end != len(b) {
+		parts = append(parts, b[beg:])
+	}
+
+	return parts
+}
+
+// NumSubexp returns the number of parenthesized subexpressions in this regexp.
+func (re *WideRegexp[T]) NumSubexp() int {
+	if re.code == 0 {
+		return 0
+	}
+
+	var n uint32
+	if re.lib.patternInfo(re.code, pcre2InfoCaptureCount, ptr(&n)) != 0 {
+		return 0
+	}
+
+	return int(n)
+}
+
+// String returns the source text used to compile the regexp.
+func (re *WideRegexp[T]) String() string {
+	return re.pattern
+}
+
+// Options returns the compile options the regexp was compiled with.
+func (re *WideRegexp[T]) Options() Option {
+	return re.opts
+}
@@ -0,0 +1,124 @@
+package pcregexp_test
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/dwisiswant0/pcregexp"
+)
+
+func compile16(t *testing.T, pattern string, opts pcregexp.Option) *pcregexp.Regexp16 {
+	t.Helper()
+
+	re, err := pcregexp.Compile16(pattern, opts)
+	if err != nil {
+		t.Skipf("Compile16(%q) error: %v", pattern, err)
+	}
+	t.Cleanup(re.Close)
+
+	return re
+}
+
+func compile32(t *testing.T, pattern string, opts pcregexp.Option) *pcregexp.Regexp32 {
+	t.Helper()
+
+	re, err := pcregexp.Compile32(pattern, opts)
+	if err != nil {
+		t.Skipf("Compile32(%q) error: %v", pattern, err)
+	}
+	t.Cleanup(re.Close)
+
+	return re
+}
+
+func TestRegexp16(t *testing.T) {
+	re := compile16(t, `(\w+)@(\w+)`, pcregexp.UTF|pcregexp.UCP)
+
+	// "𝄞" is a surrogate pair, so offsets past it are shifted by one.
+	subject := utf16.Encode([]rune("𝄞 ann@host, bob@Mail"))
+
+	if !re.Match(subject) {
+		t.Fatal("Match() = false, want true")
+	}
+
+	if got, want := re.FindIndex(subject), []int{3, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := string(utf16.Decode(re.Find(subject))), "ann@host"; got != want {
+		t.Errorf("Find() = %q, want %q", got, want)
+	}
+
+	if got, want := re.FindAllSubmatchIndex(subject, -1), [][]int{{3, 11, 3, 6, 7, 11}, {13, 21, 13, 16, 17, 21}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllSubmatchIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := re.FindAllIndex(subject, 1), [][]int{{3, 11}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllIndex(1) = %v, want %v", got, want)
+	}
+
+	got := re.ReplaceAllFunc(subject, func(b []uint16) []uint16 {
+		return utf16.Encode([]rune("<" + string(utf16.Decode(b)) + ">"))
+	})
+	if want := "𝄞 <ann@host>, <bob@Mail>"; string(utf16.Decode(got)) != want {
+		t.Errorf("ReplaceAllFunc() = %q, want %q", string(utf16.Decode(got)), want)
+	}
+
+	if re.Match(utf16.Encode([]rune("no address"))) {
+		t.Error("Match() = true, want false")
+	}
+
+	if got := re.NumSubexp(); got != 2 {
+		t.Errorf("NumSubexp() = %d, want 2", got)
+	}
+}
+
+func TestRegexp16_EmptyMatches(t *testing.T) {
+	re := compile16(t, `x*`, pcregexp.UTF)
+
+	// Empty matches step over the whole surrogate pair.
+	subject := utf16.Encode([]rune("a𝄞x"))
+
+	if got, want := re.FindAllIndex(subject, -1), [][]int{{0, 0}, {1, 1}, {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllIndex() = %v, want %v", got, want)
+	}
+
+	if got, want := string(utf16.Decode(re.ReplaceAll(subject, []uint16{'-'}))), "-a-𝄞-"; got != want {
+		t.Errorf("ReplaceAll() = %q, want %q", got, want)
+	}
+}
+
+func TestRegexp32(t *testing.T) {
+	re := compile32(t, `\s*,\s*`, pcregexp.UTF)
+
+	subject := []rune("日本 , 語,𝄞")
+
+	var parts []string
+	for _, part := range re.Split(subject, -1) {
+		parts = append(parts, string(part))
+	}
+	if want := []string{"日本", "語", "𝄞"}; !reflect.DeepEqual(parts, want) {
+		t.Errorf("Split() = %q, want %q", parts, want)
+	}
+
+	if got, want := re.FindAllIndex(subject, -1), [][]int{{2, 5}, {6, 7}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAllIndex() = %v, want %v", got, want)
+	}
+
+	if got := re.FindSubmatch([]rune("none")); got != nil {
+		t.Errorf("FindSubmatch() = %q, want nil", got)
+	}
+
+	if got, want := re.String(), `\s*,\s*`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCompile32_Error(t *testing.T) {
+	compile32(t, `a`, 0)
+
+	if _, err := pcregexp.Compile32(`a[`, 0); err == nil {
+		t.Error("Compile32() error = nil, want an error")
+	}
+}